@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lucoand/gator/internal/config"
+)
+
+func handleAgg(s *state, cmd command) error {
+	args, flags := parseFlags(cmd.args)
+	if len(args) < 1 {
+		return fmt.Errorf("ERROR: agg requires an argument.\nUsage: \"gator agg <interval> [--pidfile=<path>]\".  Interval can be of a form like 1m, 1h, etc.  Must be at least 1m.")
+	}
+	const minInterval = 1 * time.Minute
+	timeBetweenRequests, err := time.ParseDuration(args[0])
+	if err != nil {
+		fmt.Printf("ERROR: Unable to parse duration \"%v\"\n", args[0])
+		return err
+	}
+	if timeBetweenRequests < minInterval {
+		fmt.Printf("Interval too short!  Must be at least %v\n", minInterval)
+		return nil
+	}
+	pidfile := flags["pidfile"]
+	if pidfile == "" {
+		pidfile = s.backend.aggPidfile
+	}
+	if pidfile == "" {
+		return errors.New("ERROR: Couldn't find HOME directory for the pidfile.  Use --pidfile=<path>.")
+	}
+
+	release, ok, err := s.backend.lockAgg(s.ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not lock the database for agg.")
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("ERROR: Another gator agg is already collecting feeds from this database%v.", runningAgg(pidfile))
+	}
+	defer release()
+	// The lock shows that no other agg is running on this database, so a
+	// pidfile that is already there was left by one that was killed, and
+	// is replaced.  Unless its process is alive: then it belongs to an
+	// agg on another database, and is not taken over.
+	if pid, ok := pidfilePid(pidfile); ok && processAlive(pid) {
+		return fmt.Errorf("ERROR: Pidfile %v belongs to a running gator agg (pid %v) on another database.  Use --pidfile=<path>.", pidfile, pid)
+	}
+	err = os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	if err != nil {
+		fmt.Printf("ERROR: Could not write pidfile %v\n", pidfile)
+		return err
+	}
+	defer os.Remove(pidfile)
+
+	// A fetch that has started when agg is told to stop is finished, so
+	// its posts are not lost half way.  Fetches run under a context that
+	// signals do not cancel, and the http and db timeouts still bound them.
+	work := *s
+	work.ctx = context.WithoutCancel(s.ctx)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	fmt.Printf("Collecting feeds every %v, pid in %v\n", timeBetweenRequests, pidfile)
+	ticker := time.NewTicker(timeBetweenRequests)
+	defer ticker.Stop()
+	scrapeFeeds(&work)
+	for {
+		select {
+		case <-s.ctx.Done():
+			fmt.Println("Stopped collecting feeds.")
+			return nil
+		case <-hup:
+			err := reloadConfig(&work)
+			if err != nil {
+				fmt.Println(err)
+				fmt.Println("Keeping the old config.")
+				continue
+			}
+			fmt.Println("Reloaded config.")
+		case <-ticker.C:
+			// A stop that came in at the same time as the tick wins.
+			if s.ctx.Err() != nil {
+				continue
+			}
+			scrapeFeeds(&work)
+		}
+	}
+}
+
+// pidfilePid reads the pid in pidfile, and reports false if there is no
+// pidfile or it holds no pid.
+func pidfilePid(pidfile string) (int, bool) {
+	data, err := os.ReadFile(pidfile)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
+
+// processAlive reports whether a process with pid is running.  Signal 0
+// checks without sending anything; EPERM means it runs as another user.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// runningAgg describes the agg holding the lock from its pidfile, like
+// " (pid 1234)", or returns "" if the pidfile is not there.  An agg
+// started with another --pidfile, or on Postgres by another user, is not
+// found.
+func runningAgg(pidfile string) string {
+	data, err := os.ReadFile(pidfile)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(" (pid %v)", strings.TrimSpace(string(data)))
+}
+
+// reloadConfig rereads the config file into s, on SIGHUP.  Retention and
+// http_timeout take effect from the next fetch.  db_url and db_timeout
+// belong to the open database, so they need agg to be restarted.
+func reloadConfig(s *state) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("ERROR: %w", err)
+	}
+	httpTimeout, err := parseTimeout("http_timeout", cfg.HTTPTimeout, defaultHTTPTimeout)
+	if err != nil {
+		return err
+	}
+	_, err = parseTimeout("db_timeout", cfg.DBTimeout, defaultDBTimeout)
+	if err != nil {
+		return err
+	}
+	if cfg.DB_url != s.cfg.DB_url || cfg.DBTimeout != s.cfg.DBTimeout {
+		return errors.New("ERROR: db_url and db_timeout can only be changed by restarting agg.")
+	}
+	*s.cfg = cfg
+	s.client = &http.Client{Timeout: httpTimeout}
+	return nil
+}
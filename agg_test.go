@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAggPidfilePerDatabase(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	pidfiles := make(map[string]string)
+	for _, dbURL := range []string{
+		"sqlite://" + filepath.Join(dir, "a.db"),
+		"sqlite://" + filepath.Join(dir, "b.db"),
+		"postgres://localhost:5432/gator",
+		"postgres://localhost:5432/other",
+	} {
+		backend, _, err := openBackend(dbURL, 0)
+		if err != nil {
+			t.Fatalf("open %v: %v", dbURL, err)
+		}
+		if backend.db != nil {
+			backend.db.Close()
+		}
+		if backend.aggPidfile == "" {
+			t.Errorf("%v has no agg pidfile", dbURL)
+		}
+		if other, ok := pidfiles[backend.aggPidfile]; ok {
+			t.Errorf("%v and %v share the agg pidfile %v", other, dbURL, backend.aggPidfile)
+		}
+		pidfiles[backend.aggPidfile] = dbURL
+	}
+	if want := filepath.Join(dir, "a.db-agg.pid"); pidfiles[want] == "" {
+		t.Errorf("no agg pidfile %v next to the SQLite database, got %v", want, pidfiles)
+	}
+}
+
+func TestPidfilePid(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "agg.pid")
+	if _, ok := pidfilePid(pidfile); ok {
+		t.Error("found a pid without a pidfile")
+	}
+	if err := os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pid, ok := pidfilePid(pidfile)
+	if !ok || pid != os.Getpid() {
+		t.Fatalf("pidfile holds pid %v, %v, want %v", pid, ok, os.Getpid())
+	}
+	if !processAlive(pid) {
+		t.Error("the test's own process is not alive")
+	}
+}
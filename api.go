@@ -0,0 +1,524 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+const defaultServeAddr = ":8080"
+
+type apiServer struct {
+	s *state
+}
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+type apiFeed struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	CreatedBy     *string    `json:"created_by"`
+	FollowerCount int64      `json:"follower_count"`
+	PostCount     int64      `json:"post_count"`
+	LastPostAt    *time.Time `json:"last_post_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	Followed      bool       `json:"followed"`
+}
+
+type apiFollow struct {
+	FeedID   uuid.UUID `json:"feed_id"`
+	FeedName string    `json:"feed_name"`
+	FeedUrl  string    `json:"feed_url"`
+	Folder   *string   `json:"folder"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	Description string     `json:"description"`
+	PublishedAt time.Time  `json:"published_at"`
+	Read        bool       `json:"read"`
+	ReadAt      *time.Time `json:"read_at"`
+	Starred     bool       `json:"starred"`
+	StarredAt   *time.Time `json:"starred_at"`
+}
+
+func handleServe(s *state, cmd command) error {
+	addr := defaultServeAddr
+	if len(cmd.args) > 0 {
+		addr = cmd.args[0]
+	}
+	api := apiServer{s: s}
+	mux := http.NewServeMux()
+	api.registerRoutes(mux)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// On SIGINT or SIGTERM, requests already being answered get a few
+	// seconds to finish before the server stops.  s.ctx is already
+	// cancelled by then, so shutting down has a context of its own.
+	stopped := make(chan error, 1)
+	context.AfterFunc(s.ctx, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- server.Shutdown(ctx)
+	})
+	fmt.Printf("Serving gator API on %v\n", addr)
+	err := server.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	err = <-stopped
+	fmt.Println("Stopped serving.")
+	return err
+}
+
+func (api *apiServer) registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/users", api.handleUsersList)
+	mux.HandleFunc("POST /api/users", api.handleUsersCreate)
+	mux.HandleFunc("GET /api/users/{name}", api.handleUsersGet)
+	mux.HandleFunc("GET /api/feeds", api.handleFeedsList)
+	mux.HandleFunc("GET /api/feeds/{id}", api.handleFeedsGet)
+	mux.HandleFunc("POST /api/users/{name}/feeds", api.handleFeedsCreate)
+	mux.HandleFunc("GET /api/users/{name}/follows", api.handleFollowsList)
+	mux.HandleFunc("POST /api/users/{name}/follows", api.handleFollowsCreate)
+	mux.HandleFunc("DELETE /api/users/{name}/follows/{feedID}", api.handleFollowsDelete)
+	mux.HandleFunc("GET /api/users/{name}/posts", api.handlePostsList)
+	mux.HandleFunc("PUT /api/users/{name}/posts/{postID}/read", api.handlePostsRead)
+	mux.HandleFunc("DELETE /api/users/{name}/posts/{postID}/read", api.handlePostsUnread)
+	mux.HandleFunc("PUT /api/users/{name}/posts/{postID}/star", api.handlePostsStar)
+	mux.HandleFunc("DELETE /api/users/{name}/posts/{postID}/star", api.handlePostsUnstar)
+	mux.HandleFunc("GET /feeds/{name}/atom.xml", api.handleUserAtom)
+	mux.HandleFunc("GET /feeds/{name}/rss.xml", api.handleUserRSS)
+	mux.HandleFunc("GET /feeds/{name}/folders/{folder}/atom.xml", api.handleUserAtom)
+	mux.HandleFunc("GET /feeds/{name}/folders/{folder}/rss.xml", api.handleUserRSS)
+	api.registerReaderRoutes(mux)
+	api.registerFeverRoutes(mux)
+	api.registerWebRoutes(mux)
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	respondWithJSON(w, code, errorResponse{Error: msg})
+}
+
+// respondWithDBError picks a status code for an error returned by the
+// shared CLI/API logic.
+func respondWithDBError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, errInvalidArgument):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case isUniqueViolation(err):
+		respondWithError(w, http.StatusConflict, "already exists")
+	default:
+		log.Printf("Database error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func toAPIUser(user database.User) apiUser {
+	return apiUser{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Name:      user.Name,
+	}
+}
+
+func toAPIFeed(feed database.GetFeedCatalogRow) apiFeed {
+	apiFeed := apiFeed{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		Url:           feed.Url,
+		FollowerCount: feed.FollowerCount,
+		PostCount:     feed.PostCount,
+		LastPostAt:    nullTimePtr(feed.LastPostAt),
+		LastFetchedAt: nullTimePtr(feed.LastFetchedAt),
+		Followed:      feed.Followed,
+	}
+	// Feeds keep existing after the user who added them is deleted.
+	if feed.UserName.Valid {
+		apiFeed.CreatedBy = &feed.UserName.String
+	}
+	return apiFeed
+}
+
+func toAPIPost(post database.GetPostsForUserRow) apiPost {
+	return apiPost{
+		ID:          post.ID,
+		FeedID:      post.FeedID,
+		FeedName:    post.FeedName,
+		Title:       post.Title,
+		Url:         post.Url,
+		Description: post.Description,
+		PublishedAt: post.PublishedAt,
+		Read:        post.ReadAt.Valid,
+		ReadAt:      nullTimePtr(post.ReadAt),
+		Starred:     post.StarredAt.Valid,
+		StarredAt:   nullTimePtr(post.StarredAt),
+	}
+}
+
+// requestToken returns the API token sent with a request as
+// "Authorization: Bearer <token>", or else the web UI's session cookie.
+// Tokens in the url are not accepted, since urls end up in logs.
+func requestToken(r *http.Request) string {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return strings.TrimSpace(token)
+	}
+	if cookie, err := r.Cookie(webSessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// authUser returns the user whose token the request carries.  It writes
+// an error response and returns false if there is no valid token.
+func (api *apiServer) authUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	token := requestToken(r)
+	if token == "" {
+		respondWithError(w, http.StatusUnauthorized, "missing API token")
+		return database.User{}, false
+	}
+	user, err := userForToken(r.Context(), api.s, token)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "invalid API token")
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithDBError(w, err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// pathUser returns the user named in the request path, who must be the
+// owner of the request's token.  It writes an error response and returns
+// false otherwise.
+func (api *apiServer) pathUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	user, ok := api.authUser(w, r)
+	if !ok {
+		return database.User{}, false
+	}
+	if user.Name != r.PathValue("name") {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("token does not belong to user %v", r.PathValue("name")))
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// pathUUID parses the named path value as a UUID.  It writes an error
+// response and returns false if it is not one.
+func pathUUID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("invalid %v", name))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (api *apiServer) handleUsersList(w http.ResponseWriter, r *http.Request) {
+	if _, ok := api.authUser(w, r); !ok {
+		return
+	}
+	rows, err := api.s.db.ListUsers(r.Context())
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	users := []apiUser{}
+	for _, user := range rows {
+		users = append(users, toAPIUser(user))
+	}
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+func (api *apiServer) handleUsersCreate(w http.ResponseWriter, r *http.Request) {
+	if !api.s.cfg.OpenRegistration {
+		user, ok := api.authUser(w, r)
+		if !ok {
+			return
+		}
+		if user.Role != roleAdmin {
+			respondWithError(w, http.StatusForbidden, "only admins can register users")
+			return
+		}
+	}
+	var params struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	user, err := createUser(r.Context(), api.s, params.Name, params.Password)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, toAPIUser(user))
+}
+
+func (api *apiServer) handleUsersGet(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.pathUser(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, toAPIUser(user))
+}
+
+func (api *apiServer) handleFeedsList(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.authUser(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	rows, err := feedCatalog(r.Context(), api.s, user.ID, query.Get("search"), query.Get("sort"))
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	feeds := []apiFeed{}
+	for _, row := range rows {
+		feeds = append(feeds, toAPIFeed(row))
+	}
+	respondWithJSON(w, http.StatusOK, feeds)
+}
+
+func (api *apiServer) handleFeedsGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	user, ok := api.authUser(w, r)
+	if !ok {
+		return
+	}
+	rows, err := feedCatalog(r.Context(), api.s, user.ID, "", "")
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	for _, row := range rows {
+		if row.ID == id {
+			respondWithJSON(w, http.StatusOK, toAPIFeed(row))
+			return
+		}
+	}
+	respondWithError(w, http.StatusNotFound, "feed not found")
+}
+
+func (api *apiServer) handleFeedsCreate(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.pathUser(w, r)
+	if !ok {
+		return
+	}
+	var params struct {
+		Name string `json:"name"`
+		Url  string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	feed, err := createFeed(r.Context(), api.s, user, params.Name, params.Url)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, apiFeed{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		Url:           feed.Url,
+		CreatedBy:     &user.Name,
+		FollowerCount: 1,
+		Followed:      true,
+	})
+}
+
+func (api *apiServer) handleFollowsList(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.pathUser(w, r)
+	if !ok {
+		return
+	}
+	rows, err := api.s.db.GetFeedFollowsForUser(r.Context(), user.Name)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	follows := []apiFollow{}
+	for _, row := range rows {
+		follow := apiFollow{
+			FeedID:   row.FeedID,
+			FeedName: row.FeedName,
+			FeedUrl:  row.FeedUrl,
+		}
+		if row.FolderName.Valid {
+			follow.Folder = &row.FolderName.String
+		}
+		follows = append(follows, follow)
+	}
+	respondWithJSON(w, http.StatusOK, follows)
+}
+
+func (api *apiServer) handleFollowsCreate(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.pathUser(w, r)
+	if !ok {
+		return
+	}
+	var params struct {
+		FeedID uuid.UUID `json:"feed_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	_, err := api.s.db.GetFeed(r.Context(), params.FeedID)
+	if err != nil {
+		respondWithDBError(w, fmt.Errorf("feed %v not found: %w", params.FeedID, err))
+		return
+	}
+	follow, err := addFollow(r.Context(), api.s, user.ID, params.FeedID)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, apiFollow{
+		FeedID:   follow.FeedID,
+		FeedName: follow.FeedName,
+	})
+}
+
+func (api *apiServer) handleFollowsDelete(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.pathUser(w, r)
+	if !ok {
+		return
+	}
+	feedID, ok := pathUUID(w, r, "feedID")
+	if !ok {
+		return
+	}
+	var arg database.DeleteFeedFollowParams
+	arg.UserID = user.ID
+	arg.FeedID = feedID
+	count, err := api.s.db.DeleteFeedFollow(r.Context(), arg)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	if count < 1 {
+		respondWithError(w, http.StatusNotFound, "not following feed")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *apiServer) handlePostsList(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.pathUser(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	limit := 20
+	if query.Get("limit") != "" {
+		n, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = n
+	}
+	var filter postFilter
+	filter.Folder = query.Get("folder")
+	filter.UnreadOnly = query.Get("unread") == "true"
+	filter.StarredOnly = query.Get("starred") == "true"
+	rows, err := postsForUser(r.Context(), api.s, user, filter)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	posts := []apiPost{}
+	for i := 0; i < len(rows) && i < limit; i++ {
+		posts = append(posts, toAPIPost(rows[i]))
+	}
+	respondWithJSON(w, http.StatusOK, posts)
+}
+
+func (api *apiServer) handlePostsRead(w http.ResponseWriter, r *http.Request) {
+	api.markPost(w, r, setPostRead, true)
+}
+
+func (api *apiServer) handlePostsUnread(w http.ResponseWriter, r *http.Request) {
+	api.markPost(w, r, setPostRead, false)
+}
+
+func (api *apiServer) handlePostsStar(w http.ResponseWriter, r *http.Request) {
+	api.markPost(w, r, setPostStarred, true)
+}
+
+func (api *apiServer) handlePostsUnstar(w http.ResponseWriter, r *http.Request) {
+	api.markPost(w, r, setPostStarred, false)
+}
+
+func (api *apiServer) markPost(w http.ResponseWriter, r *http.Request, mark func(context.Context, *state, database.User, uuid.UUID, bool) error, value bool) {
+	user, ok := api.pathUser(w, r)
+	if !ok {
+		return
+	}
+	postID, ok := pathUUID(w, r, "postID")
+	if !ok {
+		return
+	}
+	err := mark(r.Context(), api.s, user, postID, value)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
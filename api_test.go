@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// bearer is the header that sends token to the API.
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func TestAPIMarkPostNeedsFollow(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		alice, feed := addTestBlog(t, s)
+		bob := addTestUser(t, s, "bob")
+		post := addTestPosts(t, s, feed, day, "https://example.com/a")[0]
+		if err := storeToken(s.ctx, s, alice, "laptop", tokenScopeAPI, "alice-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		if err := storeToken(s.ctx, s, bob, "laptop", tokenScopeAPI, "bob-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		server := newTestServer(t, s)
+
+		for _, mark := range []string{"read", "star"} {
+			for _, method := range []string{"PUT", "DELETE"} {
+				status, body := testRequest(t, server, method, "/api/users/bob/posts/"+post.ID.String()+"/"+mark, bearer("bob-token"), nil)
+				if status != http.StatusNotFound {
+					t.Errorf("bob %v %v of an unfollowed post: status %v, want 404: %v", method, mark, status, body)
+				}
+				status, body = testRequest(t, server, method, "/api/users/alice/posts/"+post.ID.String()+"/"+mark, bearer("alice-token"), nil)
+				if status != http.StatusNoContent {
+					t.Errorf("alice %v %v of a followed post: status %v, want 204: %v", method, mark, status, body)
+				}
+			}
+		}
+		impact, err := s.db.GetUserResetImpact(s.ctx, bob.ID)
+		if err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+		if impact.ReadCount != 0 || impact.StarCount != 0 {
+			t.Errorf("bob has %v reads and %v stars, want none", impact.ReadCount, impact.StarCount)
+		}
+	})
+}
+
+func TestAPICreateUserNeedsAdmin(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		// The first user is the admin.
+		admin := addTestUser(t, s, "alice")
+		bob := addTestUser(t, s, "bob")
+		if err := storeToken(s.ctx, s, admin, "laptop", tokenScopeAPI, "alice-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		if err := storeToken(s.ctx, s, bob, "laptop", tokenScopeAPI, "bob-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		server := newTestServer(t, s)
+		register := func(name string, header http.Header) int {
+			t.Helper()
+			body := strings.NewReader(`{"name": "` + name + `", "password": "password123"}`)
+			status, _ := testRequest(t, server, "POST", "/api/users", header, body)
+			return status
+		}
+
+		if status := register("carol", nil); status != http.StatusUnauthorized {
+			t.Errorf("register without a token: status %v, want 401", status)
+		}
+		if status := register("carol", bearer("bob-token")); status != http.StatusForbidden {
+			t.Errorf("register with a user's token: status %v, want 403", status)
+		}
+		if _, err := s.db.GetUser(s.ctx, "carol"); err == nil {
+			t.Error("carol was registered without an admin token")
+		}
+		if status := register("carol", bearer("alice-token")); status != http.StatusCreated {
+			t.Errorf("register with the admin's token: status %v, want 201", status)
+		}
+		s.cfg.OpenRegistration = true
+		if status := register("dave", nil); status != http.StatusCreated {
+			t.Errorf("register with open registration: status %v, want 201", status)
+		}
+	})
+}
+
+func TestAPITokenNotInQuery(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		alice, _ := addTestBlog(t, s)
+		if err := storeToken(s.ctx, s, alice, "laptop", tokenScopeAPI, "alice-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		server := newTestServer(t, s)
+		tests := []struct {
+			name   string
+			path   string
+			header http.Header
+			want   int
+		}{
+			{name: "query", path: "/api/users/alice?token=alice-token", want: http.StatusUnauthorized},
+			{name: "query feed", path: "/feeds/alice/atom.xml?token=alice-token", want: http.StatusUnauthorized},
+			{name: "bearer", path: "/api/users/alice", header: bearer("alice-token"), want: http.StatusOK},
+			{name: "cookie", path: "/api/users/alice", header: http.Header{"Cookie": {webSessionCookie + "=alice-token"}}, want: http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := testRequest(t, server, "GET", tt.path, tt.header, nil)
+				if status != tt.want {
+					t.Errorf("status %v, want %v: %v", status, tt.want, body)
+				}
+			})
+		}
+	})
+}
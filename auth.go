@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/config"
+	"github.com/lucoand/gator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// tokenBytes is the amount of randomness in an API token.
+const tokenBytes = 32
+
+// feverTokenName is the name of the token that holds a user's Fever
+// api_key.
+const feverTokenName = "fever"
+
+// A token's scope says where it is accepted.  Fever keys are derived from
+// a password rather than random, so they only work in the Fever API.
+// Feed tokens only open the Atom and RSS output, so a feed reader that
+// keeps them in a subscription url can read posts but change nothing.
+const (
+	tokenScopeAPI   = "api"
+	tokenScopeFever = "fever"
+	tokenScopeFeed  = "feed"
+)
+
+// Session tokens handed out by "gator login" and by the web UI login form
+// are ordinary API tokens with these names.
+const (
+	cliTokenName = "cli"
+	webTokenName = "web"
+)
+
+const minPasswordLength = 8
+
+// Users are either admins, who can run commands that affect everyone, or
+// regular users.
+const (
+	roleAdmin = "admin"
+	roleUser  = "user"
+)
+
+// errWrongPassword is returned for both unknown users and wrong
+// passwords, so callers cannot tell which user names exist.
+var errWrongPassword = errors.New("wrong user name or password")
+
+// stdin is shared by everything that reads passwords, so that piped input
+// is not lost to a second buffered reader.
+var stdin = bufio.NewReader(os.Stdin)
+
+func hashPassword(password string) (sql.NullString, error) {
+	if len(password) < minPasswordLength {
+		return sql.NullString{}, fmt.Errorf("%w: password must be at least %v characters", errInvalidArgument, minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// checkPassword returns errWrongPassword unless password is the user's
+// password.  Users without a password cannot pass it.
+func checkPassword(user database.User, password string) error {
+	if !user.PasswordHash.Valid {
+		return errWrongPassword
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	if err != nil {
+		return errWrongPassword
+	}
+	return nil
+}
+
+// authenticate looks up a user by name and checks their password.
+func authenticate(ctx context.Context, s *state, name, password string) (database.User, error) {
+	user, err := s.db.GetUser(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, errWrongPassword
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	return user, checkPassword(user, password)
+}
+
+// readPassword prompts for a password without echoing it.  When stdin is
+// not a terminal the password is read as a line, so scripts can pipe it in.
+func readPassword(ctx context.Context, prompt string) (string, error) {
+	fmt.Print(prompt)
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		// ReadPassword only turns echo back on when it returns, which
+		// it does not do if ctx is cancelled first, so it is done here.
+		oldState, err := term.GetState(fd)
+		if err != nil {
+			return "", err
+		}
+		defer term.Restore(fd, oldState)
+		password, err := readInput(ctx, func() (string, error) {
+			password, err := term.ReadPassword(fd)
+			fmt.Println()
+			return string(password), err
+		})
+		return password, err
+	}
+	return readInput(ctx, func() (string, error) {
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	})
+}
+
+// readNewPassword asks for a new password twice.
+func readNewPassword(ctx context.Context) (string, error) {
+	password, err := readPassword(ctx, "New password: ")
+	if err != nil {
+		return "", err
+	}
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("ERROR: Password must be at least %v characters.", minPasswordLength)
+	}
+	again, err := readPassword(ctx, "Repeat new password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != again {
+		return "", errors.New("ERROR: Passwords do not match.")
+	}
+	return password, nil
+}
+
+func setPassword(ctx context.Context, s *state, user database.User, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	var arg database.SetUserPasswordParams
+	arg.ID = user.ID
+	arg.PasswordHash = hash
+	return s.db.SetUserPassword(ctx, arg)
+}
+
+// endSessions revokes the user's CLI and web sessions, except the one
+// whose token is keep, so a changed password logs out whoever knew the
+// old one.  API tokens made with newtoken are left alone.
+func endSessions(ctx context.Context, s *state, user database.User, keep string) (int64, error) {
+	tokens, err := s.db.GetAPITokensForUser(ctx, user.ID)
+	if err != nil {
+		return 0, err
+	}
+	var ended int64
+	for _, token := range tokens {
+		if token.Name != cliTokenName && token.Name != webTokenName || token.TokenHash == hashToken(keep) {
+			continue
+		}
+		var arg database.DeleteAPITokenParams
+		arg.ID = token.ID
+		arg.UserID = user.ID
+		n, err := s.db.DeleteAPIToken(ctx, arg)
+		if err != nil {
+			return ended, err
+		}
+		ended += n
+	}
+	return ended, nil
+}
+
+// startSession logs the user in on this machine.  The session token from
+// an earlier login is revoked, so each config holds at most one.
+func startSession(ctx context.Context, s *state, user database.User) error {
+	if s.cfg.Token != "" {
+		err := s.db.DeleteAPITokenByHash(ctx, hashToken(s.cfg.Token))
+		if err != nil {
+			return err
+		}
+	}
+	token, err := createToken(ctx, s, user, cliTokenName)
+	if err != nil {
+		return err
+	}
+	return config.SetUser(user.Name, token, *s.cfg)
+}
+
+// currentUser returns the user commands run as.  That is the user whose
+// session token is in the config, unless --user or --token (or GATOR_USER
+// and GATOR_TOKEN) say otherwise.  Picking another user without a token
+// asks for their password.
+func currentUser(ctx context.Context, s *state) (database.User, error) {
+	name := s.cfg.Username
+	token := s.cfg.Token
+	if s.asUser != "" && s.asUser != s.cfg.Username {
+		name = s.asUser
+		token = ""
+	}
+	if s.asToken != "" {
+		token = s.asToken
+	}
+	if token == "" && s.asUser != "" {
+		password, err := readPassword(ctx, fmt.Sprintf("Password for %v: ", name))
+		if err != nil {
+			return database.User{}, err
+		}
+		user, err := authenticate(ctx, s, name, password)
+		if errors.Is(err, errWrongPassword) {
+			return database.User{}, errors.New("ERROR: Wrong password.")
+		}
+		return user, err
+	}
+	if token == "" {
+		return database.User{}, errors.New("ERROR: Not logged in.  Try \"gator login <username>\"")
+	}
+	user, err := userForToken(ctx, s, token)
+	if errors.Is(err, sql.ErrNoRows) {
+		if token == s.asToken {
+			return database.User{}, errors.New("ERROR: Invalid token.")
+		}
+		return database.User{}, fmt.Errorf("ERROR: Session has been revoked.  Log in again with \"gator login %v\"", name)
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	if s.asUser != "" && user.Name != s.asUser {
+		return database.User{}, fmt.Errorf("ERROR: Token belongs to user %v, not %v.", user.Name, s.asUser)
+	}
+	return user, nil
+}
+
+// hashToken returns the form of a token that is stored in the database.
+// Tokens are random, so a plain SHA-256 is enough; there is nothing to
+// brute force.  Fever keys are the exception: Fever clients send
+// md5("<username>:<password>") on every request, so a stolen hash can be
+// brute forced back to the Fever password.  That is why the Fever password
+// is set apart from the login one, and Fever keys only work in the Fever
+// API.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createToken makes a new API token for the user.  The token itself is
+// only returned here; the database keeps its hash.
+func createToken(ctx context.Context, s *state, user database.User, name string) (string, error) {
+	return createScopedToken(ctx, s, user, name, tokenScopeAPI)
+}
+
+// createScopedToken is createToken for tokens of any scope.
+func createScopedToken(ctx context.Context, s *state, user database.User, name, scope string) (string, error) {
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	err := storeToken(ctx, s, user, name, scope, token)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// storeToken saves the hash of token as one of the user's tokens, accepted
+// where scope says.
+func storeToken(ctx context.Context, s *state, user database.User, name, scope, token string) error {
+	var arg database.CreateAPITokenParams
+	arg.ID = uuid.New()
+	arg.CreatedAt = time.Now()
+	arg.UserID = user.ID
+	arg.Name = name
+	arg.TokenHash = hashToken(token)
+	arg.Scope = scope
+	_, err := s.db.CreateAPIToken(ctx, arg)
+	return err
+}
+
+// userForToken returns the user an API token belongs to, and records that
+// the token was used.
+func userForToken(ctx context.Context, s *state, token string) (database.User, error) {
+	return userForScopedToken(ctx, s, tokenScopeAPI, token)
+}
+
+// userForScopedToken is userForToken for tokens of any scope.
+func userForScopedToken(ctx context.Context, s *state, scope, token string) (database.User, error) {
+	tokenHash := hashToken(token)
+	var arg database.GetUserByTokenHashParams
+	arg.TokenHash = tokenHash
+	arg.Scope = scope
+	user, err := s.db.GetUserByTokenHash(ctx, arg)
+	if err != nil {
+		return database.User{}, err
+	}
+	err = s.db.TouchAPIToken(ctx, tokenHash)
+	if err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+// handleNewtoken makes an API token, or with --feed a token that only
+// opens the user's Atom and RSS feeds.
+func handleNewtoken(s *state, cmd command, user database.User) error {
+	args, flags := parseFlags(cmd.args)
+	scope := tokenScopeAPI
+	if flags["feed"] == "true" {
+		scope = tokenScopeFeed
+	}
+	name := "gator"
+	if len(args) > 0 {
+		name = args[0]
+	}
+	token, err := createScopedToken(s.ctx, s, user, name, scope)
+	if err != nil {
+		fmt.Println("ERROR: Could not create token.")
+		return err
+	}
+	fmt.Printf("Created token %v for user %v:\n\n%v\n\n", name, user.Name, token)
+	if scope == tokenScopeFeed {
+		fmt.Println("This is the only time the token is shown.  Use it as the password when subscribing to your feeds.")
+		return nil
+	}
+	fmt.Println("This is the only time the token is shown.  Use it as the password in reader apps.")
+	return nil
+}
+
+// handleFeverkey sets the password Fever apps log in with.  Fever clients
+// send md5("<username>:<password>") as their api_key, so that is what gets
+// stored, as a token named "fever" that only the Fever API accepts.
+// Setting a new password replaces the old one.
+func handleFeverkey(s *state, cmd command, user database.User) error {
+	if len(cmd.args) > 0 {
+		return fmt.Errorf("ERROR: feverkey asks for the password, so it stays out of shell history.\nUsage: gator feverkey")
+	}
+	ctx := s.ctx
+	password, err := readNewPassword(ctx)
+	if err != nil {
+		return err
+	}
+	var arg database.DeleteAPITokensByNameParams
+	arg.UserID = user.ID
+	arg.Name = feverTokenName
+	_, err = s.db.DeleteAPITokensByName(ctx, arg)
+	if err != nil {
+		fmt.Println("ERROR: Could not remove old Fever password.")
+		return err
+	}
+	sum := md5.Sum([]byte(user.Name + ":" + password))
+	err = storeToken(ctx, s, user, feverTokenName, tokenScopeFever, hex.EncodeToString(sum[:]))
+	if err != nil {
+		fmt.Println("ERROR: Could not set Fever password.")
+		return err
+	}
+	fmt.Printf("Fever password set for user %v.\n", user.Name)
+	return nil
+}
+
+func handlePasswd(s *state, cmd command, user database.User) error {
+	ctx := s.ctx
+	if user.PasswordHash.Valid {
+		password, err := readPassword(ctx, "Current password: ")
+		if err != nil {
+			return err
+		}
+		if checkPassword(user, password) != nil {
+			return errors.New("ERROR: Wrong password.")
+		}
+	}
+	password, err := readNewPassword(ctx)
+	if err != nil {
+		return err
+	}
+	var ended int64
+	err = withTx(ctx, s, func(tx *state) error {
+		if err := setPassword(ctx, tx, user, password); err != nil {
+			return err
+		}
+		ended, err = endSessions(ctx, tx, user, s.cfg.Token)
+		return err
+	})
+	if err != nil {
+		fmt.Println("ERROR: Could not change password.")
+		return err
+	}
+	fmt.Printf("Password changed for user %v.\n", user.Name)
+	if ended > 0 {
+		fmt.Printf("Logged out %v other sessions.\n", ended)
+	}
+	return nil
+}
+
+// handleSetpassword lets an admin set any user's password.  Databases from
+// before passwords existed have no user who can log in, so while no user
+// has a password, the admins' own can be set without logging in.
+func handleSetpassword(s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: Not enough arguments.\nUsage: gator setpassword <username>")
+	}
+	ctx := s.ctx
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		fmt.Printf("ERROR: User %v not registered in database!\n", cmd.args[0])
+		return err
+	}
+	users, err := s.db.ListUsers(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not retrieve users from database.")
+		return err
+	}
+	bootstrap := target.Role == roleAdmin
+	for _, user := range users {
+		if user.PasswordHash.Valid {
+			bootstrap = false
+		}
+	}
+	if !bootstrap {
+		admin, err := currentUser(ctx, s)
+		if err != nil {
+			return err
+		}
+		if admin.Role != roleAdmin {
+			return fmt.Errorf("ERROR: \"gator %v\" can only be run by an admin.  User %v is not an admin.", cmd.name, admin.Name)
+		}
+	}
+	password, err := readNewPassword(ctx)
+	if err != nil {
+		return err
+	}
+	var ended int64
+	err = withTx(ctx, s, func(tx *state) error {
+		if err := setPassword(ctx, tx, target, password); err != nil {
+			return err
+		}
+		ended, err = endSessions(ctx, tx, target, s.cfg.Token)
+		return err
+	})
+	if err != nil {
+		fmt.Println("ERROR: Could not set password.")
+		return err
+	}
+	fmt.Printf("Password set for user %v.\n", target.Name)
+	if ended > 0 {
+		fmt.Printf("Logged out %v sessions of user %v.\n", ended, target.Name)
+	}
+	return nil
+}
+
+func handleTokens(s *state, cmd command, user database.User) error {
+	tokens, err := s.db.GetAPITokensForUser(s.ctx, user.ID)
+	if err != nil {
+		fmt.Printf("ERROR: Could not get tokens for user %v\n", user.Name)
+		return err
+	}
+	if len(tokens) < 1 {
+		fmt.Printf("User %v has no tokens.\n", user.Name)
+		return nil
+	}
+	current := hashToken(s.cfg.Token)
+	fmt.Printf("Tokens for user %v:\n", user.Name)
+	for _, token := range tokens {
+		lastUsed := "never"
+		if token.LastUsedAt.Valid {
+			lastUsed = token.LastUsedAt.Time.Format(time.DateTime)
+		}
+		fmt.Printf("  %v %v  created %v, last used %v", shortID(token.ID), token.Name, token.CreatedAt.Format(time.DateTime), lastUsed)
+		if token.Scope == tokenScopeFeed {
+			fmt.Print(" (feeds only)")
+		}
+		if token.TokenHash == current {
+			fmt.Print(" (this session)")
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// handleRevoketoken deletes one of the user's tokens, picked by ID prefix
+// or by name.  Apps and sessions using it are logged out.
+func handleRevoketoken(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: Not enough arguments.\nUsage: gator revoketoken <token>")
+	}
+	ctx := s.ctx
+	tokens, err := s.db.GetAPITokensForUser(ctx, user.ID)
+	if err != nil {
+		fmt.Printf("ERROR: Could not get tokens for user %v\n", user.Name)
+		return err
+	}
+	ref := strings.ToLower(cmd.args[0])
+	var matches []database.ApiToken
+	for _, token := range tokens {
+		if strings.HasPrefix(token.ID.String(), ref) || token.Name == cmd.args[0] {
+			matches = append(matches, token)
+		}
+	}
+	if len(matches) < 1 {
+		return fmt.Errorf("ERROR: No token %v for user %v.  See \"gator tokens\"", cmd.args[0], user.Name)
+	}
+	if len(matches) > 1 {
+		return fmt.Errorf("ERROR: %v matches more than one token.  Use the ID shown by \"gator tokens\"", cmd.args[0])
+	}
+	var arg database.DeleteAPITokenParams
+	arg.ID = matches[0].ID
+	arg.UserID = user.ID
+	_, err = s.db.DeleteAPIToken(ctx, arg)
+	if err != nil {
+		fmt.Println("ERROR: Could not revoke token.")
+		return err
+	}
+	fmt.Printf("Revoked token %v %v.\n", shortID(matches[0].ID), matches[0].Name)
+	if matches[0].TokenHash == hashToken(s.cfg.Token) {
+		fmt.Println("That was this session's token; log in again to keep using gator.")
+	}
+	return nil
+}
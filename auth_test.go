@@ -0,0 +1,53 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEndSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		alice, _ := addTestBlog(t, s)
+		bob := addTestUser(t, s, "bob")
+		tokens := []struct {
+			user  string
+			name  string
+			scope string
+			token string
+		}{
+			{user: "alice", name: cliTokenName, scope: tokenScopeAPI, token: "alice-this-cli"},
+			{user: "alice", name: cliTokenName, scope: tokenScopeAPI, token: "alice-other-cli"},
+			{user: "alice", name: webTokenName, scope: tokenScopeAPI, token: "alice-web"},
+			{user: "alice", name: "laptop", scope: tokenScopeAPI, token: "alice-token"},
+			{user: "alice", name: "reader", scope: tokenScopeFeed, token: "alice-feed-token"},
+			{user: "bob", name: webTokenName, scope: tokenScopeAPI, token: "bob-web"},
+		}
+		for _, tt := range tokens {
+			user := alice
+			if tt.user == "bob" {
+				user = bob
+			}
+			if err := storeToken(s.ctx, s, user, tt.name, tt.scope, tt.token); err != nil {
+				t.Fatalf("store token: %v", err)
+			}
+		}
+
+		ended, err := endSessions(s.ctx, s, alice, "alice-this-cli")
+		if err != nil {
+			t.Fatalf("end sessions: %v", err)
+		}
+		if ended != 2 {
+			t.Errorf("ended %v sessions, want 2", ended)
+		}
+		var left []string
+		for _, tt := range tokens {
+			if _, err := userForScopedToken(s.ctx, s, tt.scope, tt.token); err == nil {
+				left = append(left, tt.token)
+			}
+		}
+		want := []string{"alice-this-cli", "alice-token", "alice-feed-token", "bob-web"}
+		if !slices.Equal(left, want) {
+			t.Errorf("tokens left = %v, want %v", left, want)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lucoand/gator/internal/database"
+	"github.com/lucoand/gator/internal/memdb"
+	"github.com/lucoand/gator/internal/migrate"
+	"github.com/lucoand/gator/internal/sqlitedb"
+	"github.com/lucoand/gator/sql/schema"
+	"github.com/lucoand/gator/sql/sqlite"
+)
+
+// backend is the database gator keeps its data in, picked by the scheme
+// of db_url: postgres:// for Postgres, sqlite: or file: for a local
+// SQLite file, and memory: for an in-memory database that is gone when
+// gator exits.  The memory backend has no schema to migrate, so db is
+// nil.
+type backend struct {
+	name       string
+	db         *sql.DB
+	dialect    migrate.Dialect
+	migrations fs.FS
+	// withTx runs fn with queries that all belong to one transaction,
+	// committed if fn returns nil and rolled back otherwise.
+	withTx func(ctx context.Context, fn func(database.Querier) error) error
+	// lockAgg takes the lock that keeps two aggs from fetching the same
+	// feeds, and reports false if another process has it.  It is held
+	// until release is called, or until gator exits however it exits.
+	lockAgg func(ctx context.Context) (release func() error, ok bool, err error)
+	// aggPidfile is where agg writes its pid unless --pidfile says
+	// otherwise.  Like the lock, it belongs to the database, so aggs on
+	// two databases do not overwrite each other's.  It is "" if there is
+	// no home directory to put it in.
+	aggPidfile string
+}
+
+// aggLockKey is the Postgres advisory lock key taken by agg.
+const aggLockKey int64 = 0x6761746f72 // "gator"
+
+// openBackend connects to db_url and returns the backend along with the
+// queries to run against it.  Each query may take at most timeout, or as
+// long as it needs if timeout is 0.
+func openBackend(dbURL string, timeout time.Duration) (*backend, database.Querier, error) {
+	scheme, _, _ := strings.Cut(dbURL, ":")
+	switch scheme {
+	case "postgres", "postgresql":
+		db, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		withTx := func(ctx context.Context, fn func(database.Querier) error) error {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			if err := fn(withQueryTimeout(database.New(tx), timeout)); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}
+		// Advisory locks belong to a session, so the lock keeps a
+		// connection of its own until it is released.
+		lockAgg := func(ctx context.Context) (func() error, bool, error) {
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			var locked bool
+			err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", aggLockKey).Scan(&locked)
+			if err != nil || !locked {
+				conn.Close()
+				return nil, false, err
+			}
+			release := func() error {
+				defer conn.Close()
+				_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", aggLockKey)
+				return err
+			}
+			return release, true, nil
+		}
+		return &backend{name: "postgres", db: db, dialect: migrate.Postgres, migrations: schema.FS, withTx: withTx, lockAgg: lockAgg, aggPidfile: homeAggPidfile(dbURL)}, withQueryTimeout(database.New(db), timeout), nil
+	case "sqlite", "file":
+		db, err := sqlitedb.Open(sqlitePath(dbURL))
+		if err != nil {
+			return nil, nil, err
+		}
+		// The generated WithTx would hand the queries a bare *sql.Tx,
+		// skipping the rewrite to SQLite, so the transaction is wrapped
+		// by sqlitedb instead.
+		withTx := func(ctx context.Context, fn func(database.Querier) error) error {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			if err := fn(withQueryTimeout(database.New(tx), timeout)); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}
+		// SQLite has no advisory locks, so agg locks a file of its own
+		// next to the database instead.
+		lockAgg := func(context.Context) (func() error, bool, error) {
+			return sqlitedb.Lock(sqlitePath(dbURL) + "-agg.lock")
+		}
+		aggPidfile := sqlitePath(dbURL) + "-agg.pid"
+		return &backend{name: "sqlite", db: db.DB, dialect: migrate.SQLite, migrations: sqlite.Schema, withTx: withTx, lockAgg: lockAgg, aggPidfile: aggPidfile}, withQueryTimeout(database.New(db), timeout), nil
+	case "memory":
+		db := memdb.New()
+		withTx := func(_ context.Context, fn func(database.Querier) error) error {
+			return db.WithTx(fn)
+		}
+		// No other process can see an in-memory database, so there is
+		// nothing to lock.
+		lockAgg := func(context.Context) (func() error, bool, error) {
+			return func() error { return nil }, true, nil
+		}
+		return &backend{name: "memory", withTx: withTx, lockAgg: lockAgg, aggPidfile: homeAggPidfile(dbURL)}, db, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported db_url %q, expected postgres://..., sqlite://<path> or memory:", dbURL)
+}
+
+// homeAggPidfile is the agg pidfile for a database that is not a local
+// file, in the home directory under a name made from db_url, like
+// ~/.gator-agg-1a2b3c4d.pid.
+func homeAggPidfile(dbURL string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(dbURL))
+	return filepath.Join(home, fmt.Sprintf(".gator-agg-%x.pid", sum[:4]))
+}
+
+// sqlitePath turns sqlite://<path>, sqlite:<path> or file:<path> into a
+// file path.  sqlite:///abs/path is absolute, and a leading ~/ is the home
+// directory.
+func sqlitePath(dbURL string) string {
+	_, path, _ := strings.Cut(dbURL, ":")
+	path = strings.TrimPrefix(path, "//")
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	return path
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+// A backup is a JSON archive of everything in the database, in a form that
+// does not depend on the backend, so it can be restored into Postgres,
+// SQLite or memory alike.  backupVersion goes up whenever the archive
+// changes in a way an older gator could not read.
+const (
+	backupFormat  = "gator-backup"
+	backupVersion = 1
+)
+
+type backupArchive struct {
+	Format      string             `json:"format"`
+	Version     int                `json:"version"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Users       []backupUser       `json:"users"`
+	Tokens      []backupToken      `json:"tokens"`
+	Feeds       []backupFeed       `json:"feeds"`
+	Folders     []backupFolder     `json:"folders"`
+	Follows     []backupFollow     `json:"follows"`
+	Posts       []backupPost       `json:"posts"`
+	Reads       []backupPostMark   `json:"reads"`
+	Stars       []backupPostMark   `json:"stars"`
+	PrunedPosts []backupPrunedPost `json:"pruned_posts"`
+}
+
+type backupUser struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Name         string    `json:"name"`
+	PasswordHash *string   `json:"password_hash"`
+	Role         string    `json:"role"`
+}
+
+type backupToken struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"token_hash"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Scope is missing from archives made before tokens had one.
+	Scope string `json:"scope,omitempty"`
+}
+
+type backupFeed struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	UserID        *uuid.UUID `json:"user_id"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	Num           int64      `json:"num"`
+	KeepDays      *int32     `json:"keep_days"`
+	KeepPosts     *int32     `json:"keep_posts"`
+}
+
+type backupFolder struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	UserID    uuid.UUID `json:"user_id"`
+	Num       int64     `json:"num"`
+}
+
+type backupFollow struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	FeedID    uuid.UUID  `json:"feed_id"`
+	FolderID  *uuid.UUID `json:"folder_id"`
+	Title     *string    `json:"title"`
+}
+
+type backupPost struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	Num         int64     `json:"num"`
+}
+
+// backupPostMark is a post a user has read, or starred.
+type backupPostMark struct {
+	UserID uuid.UUID `json:"user_id"`
+	PostID uuid.UUID `json:"post_id"`
+	At     time.Time `json:"at"`
+}
+
+type backupPrunedPost struct {
+	Url      string    `json:"url"`
+	FeedID   uuid.UUID `json:"feed_id"`
+	PrunedAt time.Time `json:"pruned_at"`
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func nullInt32Ptr(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+func ptrNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func ptrNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func ptrNullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func ptrNullInt32(n *int32) sql.NullInt32 {
+	if n == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *n, Valid: true}
+}
+
+// dumpDatabase reads every table into an archive.
+func dumpDatabase(ctx context.Context, s *state) (backupArchive, error) {
+	archive := backupArchive{
+		Format:    backupFormat,
+		Version:   backupVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+	users, err := s.db.ListUsers(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("users: %w", err)
+	}
+	for _, user := range users {
+		archive.Users = append(archive.Users, backupUser{
+			ID:           user.ID,
+			CreatedAt:    user.CreatedAt,
+			UpdatedAt:    user.UpdatedAt,
+			Name:         user.Name,
+			PasswordHash: nullStringPtr(user.PasswordHash),
+			Role:         user.Role,
+		})
+	}
+	tokens, err := s.db.GetAllAPITokens(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("tokens: %w", err)
+	}
+	for _, token := range tokens {
+		archive.Tokens = append(archive.Tokens, backupToken{
+			ID:         token.ID,
+			CreatedAt:  token.CreatedAt,
+			UserID:     token.UserID,
+			Name:       token.Name,
+			TokenHash:  token.TokenHash,
+			LastUsedAt: nullTimePtr(token.LastUsedAt),
+			Scope:      token.Scope,
+		})
+	}
+	feeds, err := s.db.GetAllFeeds(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("feeds: %w", err)
+	}
+	for _, feed := range feeds {
+		archive.Feeds = append(archive.Feeds, backupFeed{
+			ID:            feed.ID,
+			CreatedAt:     feed.CreatedAt,
+			UpdatedAt:     feed.UpdatedAt,
+			Name:          feed.Name,
+			Url:           feed.Url,
+			UserID:        nullUUIDPtr(feed.UserID),
+			LastFetchedAt: nullTimePtr(feed.LastFetchedAt),
+			Num:           feed.Num,
+			KeepDays:      nullInt32Ptr(feed.KeepDays),
+			KeepPosts:     nullInt32Ptr(feed.KeepPosts),
+		})
+	}
+	folders, err := s.db.GetAllFolders(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("folders: %w", err)
+	}
+	for _, folder := range folders {
+		archive.Folders = append(archive.Folders, backupFolder(folder))
+	}
+	follows, err := s.db.GetAllFeedFollows(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("follows: %w", err)
+	}
+	for _, follow := range follows {
+		archive.Follows = append(archive.Follows, backupFollow{
+			ID:        follow.ID,
+			CreatedAt: follow.CreatedAt,
+			UpdatedAt: follow.UpdatedAt,
+			UserID:    follow.UserID,
+			FeedID:    follow.FeedID,
+			FolderID:  nullUUIDPtr(follow.FolderID),
+			Title:     nullStringPtr(follow.Title),
+		})
+	}
+	posts, err := s.db.GetAllPosts(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("posts: %w", err)
+	}
+	for _, post := range posts {
+		archive.Posts = append(archive.Posts, backupPost(post))
+	}
+	reads, err := s.db.GetAllPostReads(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("reads: %w", err)
+	}
+	for _, read := range reads {
+		archive.Reads = append(archive.Reads, backupPostMark{UserID: read.UserID, PostID: read.PostID, At: read.ReadAt})
+	}
+	stars, err := s.db.GetAllPostStars(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("stars: %w", err)
+	}
+	for _, star := range stars {
+		archive.Stars = append(archive.Stars, backupPostMark{UserID: star.UserID, PostID: star.PostID, At: star.StarredAt})
+	}
+	pruned, err := s.db.GetAllPrunedPosts(ctx)
+	if err != nil {
+		return archive, fmt.Errorf("pruned posts: %w", err)
+	}
+	for _, post := range pruned {
+		archive.PrunedPosts = append(archive.PrunedPosts, backupPrunedPost(post))
+	}
+	return archive, nil
+}
+
+// loadDatabase writes an archive into the database, parents before the
+// rows that refer to them.  It is run in one transaction, so a failed
+// restore leaves the database empty.
+func loadDatabase(ctx context.Context, s *state, archive backupArchive) error {
+	for _, user := range archive.Users {
+		var arg database.CreateUserParams
+		arg.ID = user.ID
+		arg.CreatedAt = user.CreatedAt
+		arg.UpdatedAt = user.UpdatedAt
+		arg.Name = user.Name
+		arg.PasswordHash = ptrNullString(user.PasswordHash)
+		arg.Role = user.Role
+		if _, err := s.db.CreateUser(ctx, arg); err != nil {
+			return fmt.Errorf("user %v: %w", user.Name, err)
+		}
+	}
+	for _, token := range archive.Tokens {
+		var arg database.RestoreAPITokenParams
+		arg.ID = token.ID
+		arg.CreatedAt = token.CreatedAt
+		arg.UserID = token.UserID
+		arg.Name = token.Name
+		arg.TokenHash = token.TokenHash
+		arg.LastUsedAt = ptrNullTime(token.LastUsedAt)
+		// Before scopes, the Fever key was told apart by its name, as
+		// the migration that added them does.
+		arg.Scope = token.Scope
+		if arg.Scope == "" {
+			arg.Scope = tokenScopeAPI
+			if token.Name == feverTokenName {
+				arg.Scope = tokenScopeFever
+			}
+		}
+		if err := s.db.RestoreAPIToken(ctx, arg); err != nil {
+			return fmt.Errorf("token %v: %w", token.ID, err)
+		}
+	}
+	for _, feed := range archive.Feeds {
+		var arg database.RestoreFeedParams
+		arg.ID = feed.ID
+		arg.CreatedAt = feed.CreatedAt
+		arg.UpdatedAt = feed.UpdatedAt
+		arg.Name = feed.Name
+		arg.Url = feed.Url
+		arg.UserID = ptrNullUUID(feed.UserID)
+		arg.LastFetchedAt = ptrNullTime(feed.LastFetchedAt)
+		arg.Num = feed.Num
+		arg.KeepDays = ptrNullInt32(feed.KeepDays)
+		arg.KeepPosts = ptrNullInt32(feed.KeepPosts)
+		if err := s.db.RestoreFeed(ctx, arg); err != nil {
+			return fmt.Errorf("feed %v: %w", feed.Name, err)
+		}
+	}
+	for _, folder := range archive.Folders {
+		if err := s.db.RestoreFolder(ctx, database.RestoreFolderParams(folder)); err != nil {
+			return fmt.Errorf("folder %v: %w", folder.Name, err)
+		}
+	}
+	for _, follow := range archive.Follows {
+		var arg database.RestoreFeedFollowParams
+		arg.ID = follow.ID
+		arg.CreatedAt = follow.CreatedAt
+		arg.UpdatedAt = follow.UpdatedAt
+		arg.UserID = follow.UserID
+		arg.FeedID = follow.FeedID
+		arg.FolderID = ptrNullUUID(follow.FolderID)
+		arg.Title = ptrNullString(follow.Title)
+		if err := s.db.RestoreFeedFollow(ctx, arg); err != nil {
+			return fmt.Errorf("follow %v: %w", follow.ID, err)
+		}
+	}
+	for _, post := range archive.Posts {
+		if err := s.db.RestorePost(ctx, database.RestorePostParams(post)); err != nil {
+			return fmt.Errorf("post %v: %w", post.Url, err)
+		}
+	}
+	for _, read := range archive.Reads {
+		var arg database.MarkPostReadParams
+		arg.UserID = read.UserID
+		arg.PostID = read.PostID
+		arg.ReadAt = read.At
+		if err := s.db.MarkPostRead(ctx, arg); err != nil {
+			return fmt.Errorf("read %v: %w", read.PostID, err)
+		}
+	}
+	for _, star := range archive.Stars {
+		var arg database.StarPostParams
+		arg.UserID = star.UserID
+		arg.PostID = star.PostID
+		arg.StarredAt = star.At
+		if err := s.db.StarPost(ctx, arg); err != nil {
+			return fmt.Errorf("star %v: %w", star.PostID, err)
+		}
+	}
+	for _, post := range archive.PrunedPosts {
+		if err := s.db.RestorePrunedPost(ctx, database.RestorePrunedPostParams(post)); err != nil {
+			return fmt.Errorf("pruned post %v: %w", post.Url, err)
+		}
+	}
+	return s.db.ResetNumSequences(ctx)
+}
+
+// backupSummary counts what is in an archive, for the messages after a
+// backup or restore.
+func backupSummary(archive backupArchive) string {
+	return fmt.Sprintf("%v user(s), %v feed(s), %v follow(s), %v folder(s), %v post(s), %v read(s) and %v star(s)",
+		len(archive.Users), len(archive.Feeds), len(archive.Follows), len(archive.Folders), len(archive.Posts), len(archive.Reads), len(archive.Stars))
+}
+
+func handleBackup(s *state, cmd command, _ database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("ERROR: backup requires a file name.\nUsage: gator backup <file|->")
+	}
+	path := cmd.args[0]
+	archive, err := dumpDatabase(s.ctx, s)
+	if err != nil {
+		fmt.Println("ERROR: Could not read database for backup.")
+		return err
+	}
+	if path == "-" {
+		return writeArchive(os.Stdout, archive, false)
+	}
+	// The archive holds password and token hashes.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		fmt.Printf("ERROR: Could not create %v.  It must not already exist.\n", path)
+		return err
+	}
+	err = writeArchive(file, archive, strings.HasSuffix(path, ".gz"))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Printf("ERROR: Could not write backup to %v.\n", path)
+		return err
+	}
+	fmt.Printf("Backed up %v to %v.\n", backupSummary(archive), path)
+	return nil
+}
+
+// writeArchive writes archive to out as indented JSON, gzipped if
+// compress is set.
+func writeArchive(out io.Writer, archive backupArchive, compress bool) error {
+	if !compress {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(archive)
+	}
+	zw := gzip.NewWriter(out)
+	if err := writeArchive(zw, archive, false); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// readArchive reads and checks a backup written by handleBackup.
+func readArchive(path string) (backupArchive, error) {
+	var archive backupArchive
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return archive, err
+		}
+		defer file.Close()
+		in = file
+	}
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return archive, err
+		}
+		defer zr.Close()
+		in = zr
+	}
+	if err := json.NewDecoder(in).Decode(&archive); err != nil {
+		return archive, err
+	}
+	if archive.Format != backupFormat {
+		return archive, errors.New("not a gator backup")
+	}
+	if archive.Version > backupVersion {
+		return archive, fmt.Errorf("backup is version %v, but this gator only reads up to version %v", archive.Version, backupVersion)
+	}
+	return archive, nil
+}
+
+func handleRestore(s *state, cmd command) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("ERROR: restore requires a file name.\nUsage: gator restore <file|->")
+	}
+	ctx := s.ctx
+	archive, err := readArchive(cmd.args[0])
+	if err != nil {
+		fmt.Printf("ERROR: Could not read backup from %v.\n", cmd.args[0])
+		return err
+	}
+	// Restoring over existing data would mix two databases' users and
+	// nums, so only an empty database is accepted.
+	userCount, err := s.db.CountUsers(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not count users.")
+		return err
+	}
+	feeds, err := s.db.GetAllFeeds(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not retrieve feeds from database.")
+		return err
+	}
+	if userCount > 0 || len(feeds) > 0 {
+		return errors.New("ERROR: The database is not empty.  Restore only works on a new database, or one cleared with \"gator reset\".")
+	}
+	err = withTx(ctx, s, func(tx *state) error {
+		return loadDatabase(ctx, tx, archive)
+	})
+	if err != nil {
+		fmt.Println("ERROR: Could not restore backup.  Nothing was restored.")
+		return err
+	}
+	fmt.Printf("Restored %v from the backup made %v.\n", backupSummary(archive), archive.CreatedAt.Format(time.RFC1123))
+	return nil
+}
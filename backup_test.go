@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		name := "backup.json"
+		if compress {
+			name += ".gz"
+		}
+		t.Run(name, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, s *state) {
+				addTestData(t, s)
+				archive, err := dumpDatabase(s.ctx, s)
+				if err != nil {
+					t.Fatalf("dump: %v", err)
+				}
+				path := filepath.Join(t.TempDir(), name)
+				file, err := os.Create(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := writeArchive(file, archive, compress); err != nil {
+					t.Fatalf("write archive: %v", err)
+				}
+				file.Close()
+
+				restored := newTestState(t, s.backend.name)
+				read, err := readArchive(path)
+				if err != nil {
+					t.Fatalf("read archive: %v", err)
+				}
+				err = withTx(restored.ctx, restored, func(tx *state) error {
+					return loadDatabase(tx.ctx, tx, read)
+				})
+				if err != nil {
+					t.Fatalf("restore: %v", err)
+				}
+				again, err := dumpDatabase(restored.ctx, restored)
+				if err != nil {
+					t.Fatalf("dump restored: %v", err)
+				}
+				again.CreatedAt = archive.CreatedAt
+				if got, want := archiveJSON(t, again), archiveJSON(t, archive); got != want {
+					t.Errorf("restored database differs from the original.\ngot:\n%v\nwant:\n%v", got, want)
+				}
+
+				// New rows carry on from the restored nums.
+				user, err := restored.db.GetUser(restored.ctx, "alice")
+				if err != nil {
+					t.Fatalf("get user: %v", err)
+				}
+				feed := addTestFeed(t, restored, user, "Another", "https://another.example.com/feed")
+				if feed.Num <= archive.Feeds[len(archive.Feeds)-1].Num {
+					t.Errorf("new feed has num %v, not after the restored feeds", feed.Num)
+				}
+			})
+		})
+	}
+}
+
+func TestRestoreOldFeverToken(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		addTestData(t, s)
+		archive, err := dumpDatabase(s.ctx, s)
+		if err != nil {
+			t.Fatalf("dump: %v", err)
+		}
+		// Backups from before token scopes have no scope.
+		for i := range archive.Tokens {
+			archive.Tokens[i].Scope = ""
+		}
+		restored := newTestState(t, s.backend.name)
+		err = withTx(restored.ctx, restored, func(tx *state) error {
+			return loadDatabase(tx.ctx, tx, archive)
+		})
+		if err != nil {
+			t.Fatalf("restore: %v", err)
+		}
+		if _, err := userForScopedToken(restored.ctx, restored, tokenScopeFever, "bob-fever-key"); err != nil {
+			t.Errorf("Fever key does not work with Fever after restore: %v", err)
+		}
+		if _, err := userForToken(restored.ctx, restored, "bob-fever-key"); err == nil {
+			t.Error("Fever key works as an API token after restore")
+		}
+		if _, err := userForToken(restored.ctx, restored, "alice-token"); err != nil {
+			t.Errorf("API token does not work after restore: %v", err)
+		}
+	})
+}
+
+// archiveJSON encodes archive for comparing.
+func archiveJSON(t *testing.T, archive backupArchive) string {
+	t.Helper()
+	data, err := json.MarshalIndent(archive, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
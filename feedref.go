@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+// shortIDLength is how many characters of a feed's UUID are shown by
+// "gator feeds" and accepted as a short ID.
+const shortIDLength = 8
+
+// maxSuggestions caps the "Did you mean" list printed when a feed
+// reference matches nothing.
+const maxSuggestions = 3
+
+func shortID(id uuid.UUID) string {
+	return id.String()[:shortIDLength]
+}
+
+// resolveFeed finds the feed a user meant by ref.  ref can be the feed's
+// url, its name, its ID, or a unique prefix of its name or ID.  Exact
+// matches win over prefix matches, and a name with the same case wins over
+// one that differs in case.  When nothing matches, the closest feed names
+// are printed as suggestions.
+func resolveFeed(s *state, ref string) (database.Feed, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return database.Feed{}, errors.New("ERROR: No feed given.  Use its name, url or short ID.")
+	}
+	feeds, err := s.db.GetAllFeeds(s.ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not retrieve feeds from database.")
+		return database.Feed{}, err
+	}
+	lowerRef := strings.ToLower(ref)
+	for _, feed := range feeds {
+		if feed.Url == ref || feed.ID.String() == lowerRef || feed.Name == ref {
+			return feed, nil
+		}
+	}
+	var matches []database.Feed
+	for _, feed := range feeds {
+		if strings.EqualFold(feed.Name, ref) {
+			matches = append(matches, feed)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return database.Feed{}, ambiguousFeed(ref, matches)
+	}
+
+	matches = nil
+	for _, feed := range feeds {
+		if strings.HasPrefix(feed.ID.String(), lowerRef) || strings.HasPrefix(strings.ToLower(feed.Name), lowerRef) {
+			matches = append(matches, feed)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return database.Feed{}, ambiguousFeed(ref, matches)
+	}
+
+	fmt.Printf("ERROR: No feed matching %v in database.\n", ref)
+	suggestions := suggestFeeds(feeds, lowerRef)
+	if len(suggestions) > 0 {
+		fmt.Println("Did you mean:")
+		for _, feed := range suggestions {
+			fmt.Printf("  %v %v %v\n", shortID(feed.ID), feed.Name, feed.Url)
+		}
+	} else {
+		fmt.Println("This likely means the feed has not been added.\nTry adding with \"gator addfeed <feedname> <url>\"")
+	}
+	return database.Feed{}, fmt.Errorf("ERROR: Feed %v not found.", ref)
+}
+
+// ambiguousFeed lists the feeds ref could mean, and returns the error for
+// it.
+func ambiguousFeed(ref string, matches []database.Feed) error {
+	fmt.Printf("ERROR: %v matches more than one feed:\n", ref)
+	for _, feed := range matches {
+		fmt.Printf("  %v %v %v\n", shortID(feed.ID), feed.Name, feed.Url)
+	}
+	return fmt.Errorf("ERROR: Ambiguous feed %v.  Use the exact name, the url, or the short ID.", ref)
+}
+
+// resolvePost finds one of the user's posts by its ID or a unique prefix
+// of it, like the short IDs shown by "gator browse".
+func resolvePost(s *state, user database.User, ref string) (database.GetPostsForUserRow, error) {
+	posts, err := s.db.GetPostsForUser(s.ctx, user.ID)
+	if err != nil {
+		fmt.Printf("ERROR: Could not get posts for user %v\n", user.Name)
+		return database.GetPostsForUserRow{}, err
+	}
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	var matches []database.GetPostsForUserRow
+	for _, post := range posts {
+		if post.ID.String() == ref {
+			return post, nil
+		}
+		if strings.HasPrefix(post.ID.String(), ref) {
+			matches = append(matches, post)
+		}
+	}
+	if len(matches) > 1 {
+		return database.GetPostsForUserRow{}, fmt.Errorf("ERROR: %v matches more than one post.  Use more of the ID.", ref)
+	}
+	if len(matches) < 1 {
+		return database.GetPostsForUserRow{}, fmt.Errorf("ERROR: No post with ID %v in feeds followed by user %v.", ref, user.Name)
+	}
+	return matches[0], nil
+}
+
+// suggestFeeds returns the feeds whose name or url is close to ref, best
+// match first.
+func suggestFeeds(feeds []database.Feed, ref string) []database.Feed {
+	type candidate struct {
+		feed     database.Feed
+		distance int
+	}
+	var candidates []candidate
+	maxDistance := max(2, len(ref)/3)
+	for _, feed := range feeds {
+		name := strings.ToLower(feed.Name)
+		url := strings.ToLower(feed.Url)
+		distance := levenshtein(ref, name)
+		if strings.Contains(name, ref) || strings.Contains(url, ref) {
+			distance = 0
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{feed, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	var suggestions []database.Feed
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].feed)
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
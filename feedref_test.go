@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveFeed(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		user := addTestUser(t, s, "alice")
+		goFeed := addTestFeed(t, s, user, "Go", "https://go.dev/feed.atom")
+		goBlog := addTestFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+		rust := addTestFeed(t, s, user, "Rust Blog", "https://blog.rust-lang.org/feed.xml")
+
+		tests := []struct {
+			ref     string
+			want    string
+			wantErr bool
+		}{
+			{ref: "https://go.dev/blog/feed.atom", want: goBlog.Name},
+			{ref: "go blog", want: goBlog.Name},
+			{ref: "  Rust Blog  ", want: rust.Name},
+			// An exact name wins over the prefix it shares with Go Blog.
+			{ref: "go", want: goFeed.Name},
+			{ref: "rus", want: rust.Name},
+			{ref: goBlog.ID.String(), want: goBlog.Name},
+			{ref: strings.ToUpper(rust.ID.String()), want: rust.Name},
+			{ref: shortID(rust.ID), want: rust.Name},
+			{ref: "go b", want: goBlog.Name},
+			{ref: "g", wantErr: true},
+			{ref: "python", wantErr: true},
+			{ref: "", wantErr: true},
+			{ref: "   ", wantErr: true},
+		}
+		for _, tt := range tests {
+			feed, err := resolveFeed(s, tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveFeed(%q) = %v, want an error", tt.ref, feed.Name)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("resolveFeed(%q): %v", tt.ref, err)
+				continue
+			}
+			if feed.Name != tt.want {
+				t.Errorf("resolveFeed(%q) = %v, want %v", tt.ref, feed.Name, tt.want)
+			}
+		}
+	})
+}
+
+func TestResolveFeedEmptyRef(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		// With one feed, every prefix matches it, including "".
+		addTestBlog(t, s)
+		for _, ref := range []string{"", " "} {
+			if feed, err := resolveFeed(s, ref); err == nil {
+				t.Errorf("resolveFeed(%q) = %v, want an error", ref, feed.Name)
+			}
+		}
+	})
+}
+
+func TestResolveFeedCase(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		user := addTestUser(t, s, "alice")
+		lower := addTestFeed(t, s, user, "news", "https://a.example.com/feed")
+		upper := addTestFeed(t, s, user, "News", "https://b.example.com/feed")
+		for _, want := range []string{lower.Name, upper.Name} {
+			feed, err := resolveFeed(s, want)
+			if err != nil {
+				t.Errorf("resolveFeed(%q): %v", want, err)
+			} else if feed.Name != want {
+				t.Errorf("resolveFeed(%q) = %v, want the feed with the same case", want, feed.Name)
+			}
+		}
+		// Neither feed has this case, so it could mean either.
+		if feed, err := resolveFeed(s, "NEWS"); err == nil {
+			t.Errorf("resolveFeed(NEWS) = %v, want an ambiguity error", feed.Name)
+		}
+	})
+}
+
+func TestSuggestFeeds(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		user := addTestUser(t, s, "alice")
+		addTestFeed(t, s, user, "Hacker News", "https://news.ycombinator.com/rss")
+		addTestFeed(t, s, user, "Lobsters", "https://lobste.rs/rss")
+		feeds, err := s.db.GetAllFeeds(s.ctx)
+		if err != nil {
+			t.Fatalf("get feeds: %v", err)
+		}
+		suggestions := suggestFeeds(feeds, "lobster")
+		if len(suggestions) != 1 || suggestions[0].Name != "Lobsters" {
+			t.Errorf("suggestFeeds(lobster) = %v, want Lobsters", suggestions)
+		}
+		suggestions = suggestFeeds(feeds, "hakcer news")
+		if len(suggestions) != 1 || suggestions[0].Name != "Hacker News" {
+			t.Errorf("suggestFeeds(hakcer news) = %v, want Hacker News", suggestions)
+		}
+		if suggestions := suggestFeeds(feeds, "zzzzzz"); len(suggestions) != 0 {
+			t.Errorf("suggestFeeds(zzzzzz) = %v, want none", suggestions)
+		}
+	})
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+// The Fever API, as used by Reeder, Unread, Fiery Feeds and others.  All
+// requests go to one endpoint; query parameters such as ?api&items pick
+// what comes back, and every response carries the auth flag.  Groups are
+// gator's folders, and saved items are starred posts.
+
+const (
+	feverAPIVersion = 3
+
+	// feverMaxItems is how many items one items request returns, as the
+	// Fever spec requires.
+	feverMaxItems = 50
+)
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	Url               string `json:"url"`
+	SiteUrl           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	Html          string `json:"html"`
+	Url           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+func (api *apiServer) registerFeverRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/fever/", api.handleFever)
+}
+
+// handleFever answers a Fever API call.  A bad api_key is not an HTTP
+// error in Fever; the response just has auth set to 0.
+func (api *apiServer) handleFever(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid form")
+		return
+	}
+	response := map[string]any{"api_version": feverAPIVersion, "auth": 0}
+	user, err := userForScopedToken(r.Context(), api.s, tokenScopeFever, strings.ToLower(r.FormValue("api_key")))
+	if err != nil {
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+	response["auth"] = 1
+
+	follows, err := api.s.db.GetFeedFollowsForUser(r.Context(), user.Name)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	var lastRefreshed time.Time
+	for _, follow := range follows {
+		if follow.LastFetchedAt.Valid && follow.LastFetchedAt.Time.After(lastRefreshed) {
+			lastRefreshed = follow.LastFetchedAt.Time
+		}
+	}
+	response["last_refreshed_on_time"] = feverTime(lastRefreshed)
+
+	if r.Form.Has("mark") {
+		err = api.feverMark(r, user, follows)
+		if err != nil {
+			respondWithDBError(w, err)
+			return
+		}
+		// Fever answers a mark with the ids that changed state.
+		if as := r.FormValue("as"); as == "saved" || as == "unsaved" {
+			r.Form.Set("saved_item_ids", "")
+		} else {
+			r.Form.Set("unread_item_ids", "")
+		}
+	}
+
+	if r.Form.Has("groups") {
+		folders, err := api.s.db.GetFoldersForUser(r.Context(), user.ID)
+		if err != nil {
+			respondWithDBError(w, err)
+			return
+		}
+		groups := []feverGroup{}
+		for _, folder := range folders {
+			groups = append(groups, feverGroup{ID: folder.Num, Title: folder.Name})
+		}
+		response["groups"] = groups
+	}
+	if r.Form.Has("feeds") {
+		feeds := []feverFeed{}
+		for _, follow := range follows {
+			feeds = append(feeds, feverFeed{
+				ID:                follow.FeedNum,
+				Title:             follow.FeedName,
+				Url:               follow.FeedUrl,
+				SiteUrl:           follow.FeedUrl,
+				LastUpdatedOnTime: feverTime(follow.LastFetchedAt.Time),
+			})
+		}
+		response["feeds"] = feeds
+	}
+	if r.Form.Has("groups") || r.Form.Has("feeds") {
+		response["feeds_groups"] = feverFeedsGroups(follows)
+	}
+	if r.Form.Has("favicons") {
+		response["favicons"] = []any{}
+	}
+	if r.Form.Has("links") {
+		response["links"] = []any{}
+	}
+
+	if !r.Form.Has("items") && !r.Form.Has("unread_item_ids") && !r.Form.Has("saved_item_ids") {
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+	posts, err := postsForUser(r.Context(), api.s, user, postFilter{})
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	slices.SortFunc(posts, func(a, b database.GetPostsForUserRow) int {
+		return cmp.Compare(a.Num, b.Num)
+	})
+	if r.Form.Has("items") {
+		items, err := feverItems(r, posts, follows)
+		if err != nil {
+			respondWithDBError(w, err)
+			return
+		}
+		response["items"] = items
+		response["total_items"] = len(posts)
+	}
+	if r.Form.Has("unread_item_ids") {
+		response["unread_item_ids"] = feverItemIDs(posts, func(post database.GetPostsForUserRow) bool {
+			return !post.ReadAt.Valid
+		})
+	}
+	if r.Form.Has("saved_item_ids") {
+		response["saved_item_ids"] = feverItemIDs(posts, func(post database.GetPostsForUserRow) bool {
+			return post.StarredAt.Valid
+		})
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// feverMark handles mark=item, mark=feed and mark=group.  Feeds and groups
+// can only be marked read, and only posts gator had fetched by the before
+// time are marked, so anything that arrived since the app refreshed stays
+// unread.  Group 0 is every feed.
+func (api *apiServer) feverMark(r *http.Request, user database.User, follows []database.GetFeedFollowsForUserRow) error {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid id", errInvalidArgument)
+	}
+	as := r.FormValue("as")
+	if r.FormValue("mark") == "item" {
+		post, err := api.s.db.GetPostByNum(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		switch as {
+		case "read", "unread":
+			return setPostRead(r.Context(), api.s, user, post.ID, as == "read")
+		case "saved", "unsaved":
+			return setPostStarred(r.Context(), api.s, user, post.ID, as == "saved")
+		}
+		return fmt.Errorf("%w: cannot mark an item as %v", errInvalidArgument, as)
+	}
+	if mark := r.FormValue("mark"); mark != "feed" && mark != "group" {
+		return fmt.Errorf("%w: unknown mark %v", errInvalidArgument, mark)
+	}
+	if as != "read" {
+		return fmt.Errorf("%w: feeds and groups can only be marked read", errInvalidArgument)
+	}
+	before := time.Now()
+	if b := r.FormValue("before"); b != "" {
+		seconds, err := strconv.ParseInt(b, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid before", errInvalidArgument)
+		}
+		before = time.Unix(seconds, 0)
+	}
+
+	inScope := map[int64]bool{}
+	for _, follow := range follows {
+		switch r.FormValue("mark") {
+		case "feed":
+			inScope[follow.FeedNum] = follow.FeedNum == id
+		case "group":
+			inScope[follow.FeedNum] = id == 0 || (follow.FolderNum.Valid && follow.FolderNum.Int64 == id)
+		}
+	}
+	feedNums := feverFeedNums(follows)
+	posts, err := postsForUser(r.Context(), api.s, user, postFilter{UnreadOnly: true})
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if !inScope[feedNums[post.FeedID]] || post.CreatedAt.After(before) {
+			continue
+		}
+		err = setPostRead(r.Context(), api.s, user, post.ID, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// feverItems picks the page of items asked for by with_ids, since_id or
+// max_id.  posts must be sorted by number.
+func feverItems(r *http.Request, posts []database.GetPostsForUserRow, follows []database.GetFeedFollowsForUserRow) ([]feverItem, error) {
+	var page []database.GetPostsForUserRow
+	switch {
+	case r.Form.Has("with_ids"):
+		var ids []int64
+		for _, field := range strings.Split(r.FormValue("with_ids"), ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid with_ids", errInvalidArgument)
+			}
+			ids = append(ids, id)
+		}
+		for _, post := range posts {
+			if slices.Contains(ids, post.Num) && len(page) < feverMaxItems {
+				page = append(page, post)
+			}
+		}
+	case r.Form.Has("max_id"):
+		maxID, err := strconv.ParseInt(r.FormValue("max_id"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid max_id", errInvalidArgument)
+		}
+		for i := len(posts) - 1; i >= 0 && len(page) < feverMaxItems; i-- {
+			if posts[i].Num < maxID {
+				page = append(page, posts[i])
+			}
+		}
+	default:
+		var sinceID int64
+		if r.Form.Has("since_id") {
+			var err error
+			sinceID, err = strconv.ParseInt(r.FormValue("since_id"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid since_id", errInvalidArgument)
+			}
+		}
+		for _, post := range posts {
+			if post.Num > sinceID && len(page) < feverMaxItems {
+				page = append(page, post)
+			}
+		}
+	}
+
+	feedNums := feverFeedNums(follows)
+	items := []feverItem{}
+	for _, post := range page {
+		items = append(items, feverItem{
+			ID:            post.Num,
+			FeedID:        feedNums[post.FeedID],
+			Title:         post.Title,
+			Html:          post.Description,
+			Url:           post.Url,
+			IsSaved:       feverBool(post.StarredAt.Valid),
+			IsRead:        feverBool(post.ReadAt.Valid),
+			CreatedOnTime: feverTime(post.PublishedAt),
+		})
+	}
+	return items, nil
+}
+
+func feverFeedsGroups(follows []database.GetFeedFollowsForUserRow) []feverFeedsGroup {
+	feedIDs := map[int64][]string{}
+	var groupIDs []int64
+	for _, follow := range follows {
+		if !follow.FolderNum.Valid {
+			continue
+		}
+		groupID := follow.FolderNum.Int64
+		if _, ok := feedIDs[groupID]; !ok {
+			groupIDs = append(groupIDs, groupID)
+		}
+		feedIDs[groupID] = append(feedIDs[groupID], strconv.FormatInt(follow.FeedNum, 10))
+	}
+	feedsGroups := []feverFeedsGroup{}
+	for _, groupID := range groupIDs {
+		feedsGroups = append(feedsGroups, feverFeedsGroup{GroupID: groupID, FeedIDs: strings.Join(feedIDs[groupID], ",")})
+	}
+	return feedsGroups
+}
+
+// feverFeedNums maps feed UUIDs to the numbers Fever knows them by.
+func feverFeedNums(follows []database.GetFeedFollowsForUserRow) map[uuid.UUID]int64 {
+	nums := map[uuid.UUID]int64{}
+	for _, follow := range follows {
+		nums[follow.FeedID] = follow.FeedNum
+	}
+	return nums
+}
+
+// feverItemIDs returns the numbers of the posts that match as the comma
+// separated string Fever uses.
+func feverItemIDs(posts []database.GetPostsForUserRow, match func(database.GetPostsForUserRow) bool) string {
+	var ids []string
+	for _, post := range posts {
+		if match(post) {
+			ids = append(ids, strconv.FormatInt(post.Num, 10))
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+func feverTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func feverBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
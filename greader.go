@@ -0,0 +1,579 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucoand/gator/internal/database"
+)
+
+// The Google Reader API as spoken by FreshRSS, Miniflux and the mobile
+// apps that sync with them.  Only the parts needed to read and sync are
+// implemented: logging in, listing subscriptions and tags, fetching
+// streams, and setting read and starred state.
+
+const (
+	readerItemPrefix  = "tag:google.com,2005:reader/item/"
+	readerReadingList = "user/-/state/com.google/reading-list"
+	readerRead        = "user/-/state/com.google/read"
+	readerStarred     = "user/-/state/com.google/starred"
+	readerKeptUnread  = "user/-/state/com.google/kept-unread"
+	readerLabelPrefix = "user/-/label/"
+	readerFeedPrefix  = "feed/"
+
+	// readerDefaultCount and readerMaxCount bound the n parameter of the
+	// stream endpoints.
+	readerDefaultCount = 20
+	readerMaxCount     = 1000
+)
+
+type readerLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HtmlUrl  string `json:"htmlUrl"`
+}
+
+type readerContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type readerItem struct {
+	ID            string        `json:"id"`
+	CrawlTimeMsec string        `json:"crawlTimeMsec"`
+	TimestampUsec string        `json:"timestampUsec"`
+	Published     int64         `json:"published"`
+	Updated       int64         `json:"updated"`
+	Title         string        `json:"title"`
+	Canonical     []readerLink  `json:"canonical"`
+	Alternate     []readerLink  `json:"alternate"`
+	Categories    []string      `json:"categories"`
+	Origin        readerOrigin  `json:"origin"`
+	Summary       readerContent `json:"summary"`
+	Author        string        `json:"author"`
+}
+
+type readerCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type readerSubscription struct {
+	ID         string           `json:"id"`
+	Title      string           `json:"title"`
+	Categories []readerCategory `json:"categories"`
+	Url        string           `json:"url"`
+	HtmlUrl    string           `json:"htmlUrl"`
+	IconUrl    string           `json:"iconUrl"`
+}
+
+type readerTag struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerUnreadCount struct {
+	ID                      string `json:"id"`
+	Count                   int    `json:"count"`
+	NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+}
+
+type readerItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+func (api *apiServer) registerReaderRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /accounts/ClientLogin", api.handleReaderLogin)
+	mux.HandleFunc("GET /reader/api/0/token", api.readerAuth(api.handleReaderToken))
+	mux.HandleFunc("GET /reader/api/0/user-info", api.readerAuth(api.handleReaderUserInfo))
+	mux.HandleFunc("GET /reader/api/0/subscription/list", api.readerAuth(api.handleReaderSubscriptions))
+	mux.HandleFunc("GET /reader/api/0/tag/list", api.readerAuth(api.handleReaderTags))
+	mux.HandleFunc("GET /reader/api/0/unread-count", api.readerAuth(api.handleReaderUnreadCount))
+	mux.HandleFunc("GET /reader/api/0/stream/contents", api.readerAuth(api.handleReaderStreamContents))
+	mux.HandleFunc("GET /reader/api/0/stream/contents/{streamID...}", api.readerAuth(api.handleReaderStreamContents))
+	mux.HandleFunc("GET /reader/api/0/stream/items/ids", api.readerAuth(api.handleReaderItemIDs))
+	mux.HandleFunc("/reader/api/0/stream/items/contents", api.readerAuth(api.handleReaderItemContents))
+	mux.HandleFunc("POST /reader/api/0/edit-tag", api.readerAuth(api.handleReaderEditTag))
+	mux.HandleFunc("POST /reader/api/0/mark-all-as-read", api.readerAuth(api.handleReaderMarkAllRead))
+}
+
+// readerAuth checks the "Authorization: GoogleLogin auth=<token>" header
+// that reader apps send after ClientLogin.
+func (api *apiServer) readerAuth(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !found || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := userForToken(r.Context(), api.s, token)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// handleReaderLogin implements ClientLogin.  Email is the gator user name
+// and Passwd is an API token made with "gator newtoken".  Both are only
+// read from the POST body, so the token stays out of urls and logs.
+func (api *apiServer) handleReaderLogin(w http.ResponseWriter, r *http.Request) {
+	name := r.PostFormValue("Email")
+	token := r.PostFormValue("Passwd")
+	user, err := userForToken(r.Context(), api.s, token)
+	if err != nil || user.Name != name {
+		http.Error(w, "Error=BadAuthentication", http.StatusForbidden)
+		return
+	}
+	if r.FormValue("output") == "json" {
+		respondWithJSON(w, http.StatusOK, map[string]string{"SID": token, "LSID": token, "Auth": token})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%v\nLSID=%v\nAuth=%v\n", token, token, token)
+}
+
+// handleReaderToken returns the token apps send back as T on edits.  gator
+// relies on the Authorization header instead, so any value will do.
+func (api *apiServer) handleReaderToken(w http.ResponseWriter, r *http.Request, user database.User) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, hashToken(user.ID.String())[:57])
+}
+
+func (api *apiServer) handleReaderUserInfo(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     user.Name,
+	})
+}
+
+func (api *apiServer) handleReaderSubscriptions(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := api.s.db.GetFeedFollowsForUser(r.Context(), user.Name)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	subscriptions := []readerSubscription{}
+	for _, follow := range follows {
+		sub := readerSubscription{
+			ID:         readerFeedPrefix + follow.FeedID.String(),
+			Title:      follow.FeedName,
+			Categories: []readerCategory{},
+			Url:        follow.FeedUrl,
+			HtmlUrl:    follow.FeedUrl,
+		}
+		if follow.FolderName.Valid {
+			sub.Categories = append(sub.Categories, readerCategory{
+				ID:    readerLabelPrefix + follow.FolderName.String,
+				Label: follow.FolderName.String,
+			})
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"subscriptions": subscriptions})
+}
+
+func (api *apiServer) handleReaderTags(w http.ResponseWriter, r *http.Request, user database.User) {
+	folders, err := api.s.db.GetFoldersForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	tags := []readerTag{{ID: readerStarred}}
+	for _, folder := range folders {
+		tags = append(tags, readerTag{ID: readerLabelPrefix + folder.Name, Type: "folder"})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (api *apiServer) handleReaderUnreadCount(w http.ResponseWriter, r *http.Request, user database.User) {
+	posts, err := postsForUser(r.Context(), api.s, user, postFilter{UnreadOnly: true})
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	counts := map[string]*readerUnreadCount{}
+	var order []string
+	add := func(streamID string, post database.GetPostsForUserRow) {
+		count, ok := counts[streamID]
+		if !ok {
+			// Posts are newest first, so the first one seen is the newest.
+			count = &readerUnreadCount{ID: streamID, NewestItemTimestampUsec: readerUsec(post.PublishedAt)}
+			counts[streamID] = count
+			order = append(order, streamID)
+		}
+		count.Count++
+	}
+	for _, post := range posts {
+		add(readerReadingList, post)
+		add(readerFeedPrefix+post.FeedID.String(), post)
+		if post.FolderName.Valid {
+			add(readerLabelPrefix+post.FolderName.String, post)
+		}
+	}
+	unreadCounts := []readerUnreadCount{}
+	for _, streamID := range order {
+		unreadCounts = append(unreadCounts, *counts[streamID])
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"max":          len(posts),
+		"unreadcounts": unreadCounts,
+	})
+}
+
+func (api *apiServer) handleReaderStreamContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	streamID := r.PathValue("streamID")
+	if streamID == "" {
+		streamID = r.FormValue("s")
+	}
+	if streamID == "" {
+		streamID = readerReadingList
+	}
+	posts, continuation, err := api.readerStream(r, user, streamID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	items := []readerItem{}
+	for _, post := range posts {
+		items = append(items, toReaderItem(post))
+	}
+	response := map[string]any{
+		"direction": "ltr",
+		"id":        streamID,
+		"title":     streamID,
+		"updated":   time.Now().Unix(),
+		"items":     items,
+	}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (api *apiServer) handleReaderItemIDs(w http.ResponseWriter, r *http.Request, user database.User) {
+	streamID := r.FormValue("s")
+	if streamID == "" {
+		streamID = readerReadingList
+	}
+	posts, continuation, err := api.readerStream(r, user, streamID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	refs := []readerItemRef{}
+	for _, post := range posts {
+		refs = append(refs, readerItemRef{
+			ID:              strconv.FormatInt(post.Num, 10),
+			DirectStreamIDs: []string{},
+			TimestampUsec:   readerUsec(post.PublishedAt),
+		})
+	}
+	response := map[string]any{"itemRefs": refs}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (api *apiServer) handleReaderItemContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	if err := r.ParseForm(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid form")
+		return
+	}
+	nums, err := parseReaderItemIDs(r.Form["i"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	posts, err := postsForUser(r.Context(), api.s, user, postFilter{})
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	items := []readerItem{}
+	for _, post := range posts {
+		if slices.Contains(nums, post.Num) {
+			items = append(items, toReaderItem(post))
+		}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"direction": "ltr",
+		"id":        readerReadingList,
+		"updated":   time.Now().Unix(),
+		"items":     items,
+	})
+}
+
+func (api *apiServer) handleReaderEditTag(w http.ResponseWriter, r *http.Request, user database.User) {
+	if err := r.ParseForm(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid form")
+		return
+	}
+	nums, err := parseReaderItemIDs(r.Form["i"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, num := range nums {
+		post, err := api.s.db.GetPostByNum(r.Context(), num)
+		if err != nil {
+			respondWithDBError(w, fmt.Errorf("item %v not found: %w", num, err))
+			return
+		}
+		for _, tag := range r.Form["a"] {
+			err = applyReaderTag(r, api.s, user, post, normalizeStreamID(tag), true)
+			if err != nil {
+				respondWithDBError(w, err)
+				return
+			}
+		}
+		for _, tag := range r.Form["r"] {
+			err = applyReaderTag(r, api.s, user, post, normalizeStreamID(tag), false)
+			if err != nil {
+				respondWithDBError(w, err)
+				return
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
+
+// applyReaderTag adds or removes one edit-tag state on a post.  Labels on
+// single items are not supported and are ignored.
+func applyReaderTag(r *http.Request, s *state, user database.User, post database.Post, tag string, add bool) error {
+	switch tag {
+	case readerRead:
+		return setPostRead(r.Context(), s, user, post.ID, add)
+	case readerKeptUnread:
+		if add {
+			return setPostRead(r.Context(), s, user, post.ID, false)
+		}
+	case readerStarred:
+		return setPostStarred(r.Context(), s, user, post.ID, add)
+	}
+	return nil
+}
+
+func (api *apiServer) handleReaderMarkAllRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	streamID := normalizeStreamID(r.FormValue("s"))
+	if streamID == "" {
+		respondWithError(w, http.StatusBadRequest, "missing s")
+		return
+	}
+	// ts is in microseconds; only posts older than it are marked, so items
+	// that arrived after the app last refreshed stay unread.
+	var olderThan time.Time
+	if ts := r.FormValue("ts"); ts != "" {
+		usec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid ts")
+			return
+		}
+		olderThan = time.UnixMicro(usec)
+	}
+	posts, err := postsForUser(r.Context(), api.s, user, postFilter{UnreadOnly: true})
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	for _, post := range posts {
+		if !matchesStream(post, streamID) {
+			continue
+		}
+		if !olderThan.IsZero() && post.CreatedAt.After(olderThan) {
+			continue
+		}
+		err = setPostRead(r.Context(), api.s, user, post.ID, true)
+		if err != nil {
+			respondWithDBError(w, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
+
+// readerStream applies the stream parameters shared by stream/contents
+// and stream/items/ids: xt and it to exclude or require a state, ot and
+// nt as the start and end of a time range in seconds, r=o for oldest
+// first, n for the page size and c for the continuation returned by the
+// previous page.
+func (api *apiServer) readerStream(r *http.Request, user database.User, streamID string) ([]database.GetPostsForUserRow, string, error) {
+	streamID = normalizeStreamID(streamID)
+	posts, err := postsForUser(r.Context(), api.s, user, postFilter{})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, "", err
+	}
+	exclude := r.Form["xt"]
+	include := r.Form["it"]
+	// The range is compared with when gator fetched a post, like ts in
+	// mark-all-as-read, so a post published with an old date still reaches
+	// an app that syncs from its last sync time.
+	var start, stop int64
+	if ot := r.FormValue("ot"); ot != "" {
+		start, err = strconv.ParseInt(ot, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid ot")
+		}
+	}
+	if nt := r.FormValue("nt"); nt != "" {
+		stop, err = strconv.ParseInt(nt, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid nt")
+		}
+	}
+
+	var matches []database.GetPostsForUserRow
+	for _, post := range posts {
+		if !matchesStream(post, streamID) {
+			continue
+		}
+		if slices.ContainsFunc(exclude, func(xt string) bool { return matchesStream(post, normalizeStreamID(xt)) }) {
+			continue
+		}
+		if !slices.ContainsFunc(include, func(it string) bool { return matchesStream(post, normalizeStreamID(it)) }) && len(include) > 0 {
+			continue
+		}
+		if start > 0 && post.CreatedAt.Unix() < start {
+			continue
+		}
+		if stop > 0 && post.CreatedAt.Unix() > stop {
+			continue
+		}
+		matches = append(matches, post)
+	}
+	if r.FormValue("r") == "o" {
+		slices.Reverse(matches)
+	}
+
+	count := readerDefaultCount
+	if n := r.FormValue("n"); n != "" {
+		count, err = strconv.Atoi(n)
+		if err != nil || count < 1 {
+			return nil, "", fmt.Errorf("invalid n")
+		}
+		count = min(count, readerMaxCount)
+	}
+	offset := 0
+	if c := r.FormValue("c"); c != "" {
+		offset, err = strconv.Atoi(c)
+		if err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("invalid c")
+		}
+	}
+	if offset >= len(matches) {
+		return nil, "", nil
+	}
+	end := min(offset+count, len(matches))
+	continuation := ""
+	if end < len(matches) {
+		continuation = strconv.Itoa(end)
+	}
+	return matches[offset:end], continuation, nil
+}
+
+// normalizeStreamID rewrites "user/<id>/..." to the "user/-/..." form, which
+// apps use interchangeably.
+func normalizeStreamID(streamID string) string {
+	rest, found := strings.CutPrefix(streamID, "user/")
+	if !found {
+		return streamID
+	}
+	_, tail, found := strings.Cut(rest, "/")
+	if !found {
+		return streamID
+	}
+	return "user/-/" + tail
+}
+
+func matchesStream(post database.GetPostsForUserRow, streamID string) bool {
+	switch {
+	case streamID == readerReadingList:
+		return true
+	case streamID == readerRead:
+		return post.ReadAt.Valid
+	case streamID == readerStarred:
+		return post.StarredAt.Valid
+	case strings.HasPrefix(streamID, readerLabelPrefix):
+		return post.FolderName.Valid && post.FolderName.String == strings.TrimPrefix(streamID, readerLabelPrefix)
+	case strings.HasPrefix(streamID, readerFeedPrefix):
+		return post.FeedID.String() == strings.TrimPrefix(streamID, readerFeedPrefix)
+	}
+	return false
+}
+
+// parseReaderItemIDs accepts both the long form
+// "tag:google.com,2005:reader/item/<16 hex digits>" and the decimal short
+// form of item IDs.
+func parseReaderItemIDs(ids []string) ([]int64, error) {
+	var nums []int64
+	for _, id := range ids {
+		if hexID, found := strings.CutPrefix(id, readerItemPrefix); found {
+			num, err := strconv.ParseUint(hexID, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid item id %v", id)
+			}
+			nums = append(nums, int64(num))
+			continue
+		}
+		num, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item id %v", id)
+		}
+		nums = append(nums, num)
+	}
+	return nums, nil
+}
+
+func readerUsec(t time.Time) string {
+	return strconv.FormatInt(t.UnixMicro(), 10)
+}
+
+func toReaderItem(post database.GetPostsForUserRow) readerItem {
+	categories := []string{readerReadingList}
+	if post.ReadAt.Valid {
+		categories = append(categories, readerRead)
+	}
+	if post.StarredAt.Valid {
+		categories = append(categories, readerStarred)
+	}
+	if post.FolderName.Valid {
+		categories = append(categories, readerLabelPrefix+post.FolderName.String)
+	}
+	return readerItem{
+		ID:            fmt.Sprintf("%v%016x", readerItemPrefix, uint64(post.Num)),
+		CrawlTimeMsec: strconv.FormatInt(post.CreatedAt.UnixMilli(), 10),
+		TimestampUsec: readerUsec(post.PublishedAt),
+		Published:     post.PublishedAt.Unix(),
+		Updated:       post.UpdatedAt.Unix(),
+		Title:         post.Title,
+		Canonical:     []readerLink{{Href: post.Url}},
+		Alternate:     []readerLink{{Href: post.Url, Type: "text/html"}},
+		Categories:    categories,
+		Origin: readerOrigin{
+			StreamID: readerFeedPrefix + post.FeedID.String(),
+			Title:    post.FeedName,
+			HtmlUrl:  post.FeedUrl,
+		},
+		Summary: readerContent{Direction: "ltr", Content: post.Description},
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReaderStreamTimeRange(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "all", query: "", want: []string{"3", "2", "1"}},
+		{name: "start", query: fmt.Sprintf("ot=%v", now.Add(-60*time.Hour).Unix()), want: []string{"3", "2"}},
+		{name: "stop", query: fmt.Sprintf("nt=%v", now.Add(-36*time.Hour).Unix()), want: []string{"2", "1"}},
+		{name: "both", query: fmt.Sprintf("ot=%v&nt=%v", now.Add(-60*time.Hour).Unix(), now.Add(-36*time.Hour).Unix()), want: []string{"2"}},
+		{name: "exact", query: fmt.Sprintf("ot=%v&nt=%v", now.Add(-2*day).Unix(), now.Add(-2*day).Unix()), want: []string{"2"}},
+	}
+	forEachBackend(t, func(t *testing.T, s *state) {
+		user, feed := addTestBlog(t, s)
+		if err := storeToken(s.ctx, s, user, "reader", tokenScopeAPI, "alice-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		addTestPostFetchedAt(t, s, feed, "https://example.com/1", now.Add(-3*day), 1)
+		addTestPostFetchedAt(t, s, feed, "https://example.com/2", now.Add(-2*day), 2)
+		addTestPostFetchedAt(t, s, feed, "https://example.com/3", now.Add(-day), 3)
+		server := newTestServer(t, s)
+		header := http.Header{"Authorization": {"GoogleLogin auth=alice-token"}}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := testRequest(t, server, "GET", "/reader/api/0/stream/items/ids?"+tt.query, header, nil)
+				if status != http.StatusOK {
+					t.Fatalf("status %v: %v", status, body)
+				}
+				var response struct {
+					ItemRefs []struct {
+						ID string `json:"id"`
+					} `json:"itemRefs"`
+				}
+				if err := json.Unmarshal([]byte(body), &response); err != nil {
+					t.Fatalf("decode %q: %v", body, err)
+				}
+				var got []string
+				for _, ref := range response.ItemRefs {
+					got = append(got, ref.ID)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("items = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+}
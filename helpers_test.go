@@ -0,0 +1,274 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/config"
+	"github.com/lucoand/gator/internal/database"
+	"github.com/lucoand/gator/internal/migrate"
+)
+
+const day = 24 * time.Hour
+
+// testBackends are the databases every test runs against.  Postgres
+// needs a server, so it is left out.
+var testBackends = []string{"memory", "sqlite"}
+
+// forEachBackend runs test as a subtest against a new, empty database of
+// each of testBackends.
+func forEachBackend(t *testing.T, test func(t *testing.T, s *state)) {
+	for _, name := range testBackends {
+		t.Run(name, func(t *testing.T) {
+			test(t, newTestState(t, name))
+		})
+	}
+}
+
+// newTestState opens a new database of the named backend, with its
+// schema migrated, and a state to run commands against it.
+func newTestState(t *testing.T, name string) *state {
+	t.Helper()
+	dbURL := "memory:"
+	if name == "sqlite" {
+		dbURL = "sqlite://" + filepath.Join(t.TempDir(), "gator.db")
+	}
+	backend, db, err := openBackend(dbURL, 0)
+	if err != nil {
+		t.Fatalf("open %v: %v", dbURL, err)
+	}
+	if backend.db != nil {
+		t.Cleanup(func() { backend.db.Close() })
+		migrations, err := migrate.Load(backend.migrations)
+		if err != nil {
+			t.Fatalf("load migrations: %v", err)
+		}
+		err = migrate.Up(t.Context(), backend.db, backend.dialect, migrations, func(migrate.Migration) {})
+		if err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+	}
+	var s state
+	s.db = db
+	s.cfg = &config.Config{DB_url: dbURL}
+	s.ctx = t.Context()
+	s.client = &http.Client{}
+	s.backend = backend
+	return &s
+}
+
+func addTestUser(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+	user, err := createUser(s.ctx, s, name, "password123")
+	if err != nil {
+		t.Fatalf("create user %v: %v", name, err)
+	}
+	return user
+}
+
+func addTestFeed(t *testing.T, s *state, user database.User, name, url string) database.Feed {
+	t.Helper()
+	feed, err := createFeed(s.ctx, s, user, name, url)
+	if err != nil {
+		t.Fatalf("create feed %v: %v", name, err)
+	}
+	return feed
+}
+
+// addTestPosts stores a post for each of urls, published age ago, and
+// returns the ones that were new.
+func addTestPosts(t *testing.T, s *state, feed database.Feed, age time.Duration, urls ...string) []database.Post {
+	t.Helper()
+	var arg database.CreatePostsParams
+	arg.FeedID = feed.ID
+	for _, url := range urls {
+		arg.Ids = append(arg.Ids, uuid.New())
+		arg.Titles = append(arg.Titles, "Post "+url)
+		arg.Urls = append(arg.Urls, url)
+		arg.Descriptions = append(arg.Descriptions, "About "+url)
+		arg.PublishedAts = append(arg.PublishedAts, time.Now().Add(-age))
+	}
+	posts, err := s.db.CreatePosts(s.ctx, arg)
+	if err != nil {
+		t.Fatalf("create posts: %v", err)
+	}
+	return posts
+}
+
+// addTestPostFetchedAt stores a post as if gator had fetched it at
+// fetched, numbered num.
+func addTestPostFetchedAt(t *testing.T, s *state, feed database.Feed, url string, fetched time.Time, num int64) {
+	t.Helper()
+	var arg database.RestorePostParams
+	arg.ID = uuid.New()
+	arg.CreatedAt = fetched
+	arg.UpdatedAt = fetched
+	arg.Title = "Post " + url
+	arg.Url = url
+	arg.Description = "About " + url
+	arg.PublishedAt = fetched
+	arg.FeedID = feed.ID
+	arg.Num = num
+	if err := s.db.RestorePost(s.ctx, arg); err != nil {
+		t.Fatalf("restore post: %v", err)
+	}
+}
+
+func starTestPost(t *testing.T, s *state, user database.User, post database.Post) {
+	t.Helper()
+	var arg database.StarPostParams
+	arg.UserID = user.ID
+	arg.PostID = post.ID
+	arg.StarredAt = time.Now()
+	if err := s.db.StarPost(s.ctx, arg); err != nil {
+		t.Fatalf("star post: %v", err)
+	}
+}
+
+func readTestPost(t *testing.T, s *state, user database.User, post database.Post) {
+	t.Helper()
+	var arg database.MarkPostReadParams
+	arg.UserID = user.ID
+	arg.PostID = post.ID
+	arg.ReadAt = time.Now()
+	if err := s.db.MarkPostRead(s.ctx, arg); err != nil {
+		t.Fatalf("mark post read: %v", err)
+	}
+}
+
+// postURLs returns the urls of every post in the database, in the
+// order GetAllPosts gives them.
+func postURLs(t *testing.T, s *state) []string {
+	t.Helper()
+	posts, err := s.db.GetAllPosts(s.ctx)
+	if err != nil {
+		t.Fatalf("get posts: %v", err)
+	}
+	var urls []string
+	for _, post := range posts {
+		urls = append(urls, post.Url)
+	}
+	return urls
+}
+
+// newTestServer serves the routes of "gator serve" from s until the test
+// ends.
+func newTestServer(t *testing.T, s *state) *httptest.Server {
+	t.Helper()
+	api := apiServer{s: s}
+	mux := http.NewServeMux()
+	api.registerRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testRequest sends a request with the given header to server and returns
+// the response status and body.
+func testRequest(t *testing.T, server *httptest.Server, method, path string, header http.Header, body io.Reader) (int, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), method, server.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%v %v: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("read %v %v: %v", method, path, err)
+	}
+	return res.StatusCode, string(data)
+}
+
+// addTestBlog adds user alice, who follows the feed Blog, for tests that
+// need only one of each.
+func addTestBlog(t *testing.T, s *state) (database.User, database.Feed) {
+	t.Helper()
+	user := addTestUser(t, s, "alice")
+	return user, addTestFeed(t, s, user, "Blog", "https://example.com/feed.xml")
+}
+
+// testData is a small database with a row in every table, shared by the
+// backup and reset tests.
+type testData struct {
+	alice, bob database.User
+	blog, news database.Feed
+	posts      []database.Post
+}
+
+// addTestData fills s with two users who follow two feeds, with a folder,
+// a custom title, tokens, read and starred posts, and a pruned post.
+func addTestData(t *testing.T, s *state) testData {
+	t.Helper()
+	ctx := s.ctx
+	var data testData
+	data.alice = addTestUser(t, s, "alice")
+	data.bob = addTestUser(t, s, "bob")
+	data.blog = addTestFeed(t, s, data.alice, "Blog", "https://example.com/feed.xml")
+	data.news = addTestFeed(t, s, data.bob, "News", "https://news.example.com/rss")
+	if _, err := addFollow(ctx, s, data.bob.ID, data.blog.ID); err != nil {
+		t.Fatalf("follow: %v", err)
+	}
+	if err := s.db.MarkFeedFetched(ctx, data.blog.ID); err != nil {
+		t.Fatalf("mark feed fetched: %v", err)
+	}
+
+	var folderArg database.CreateFolderParams
+	folderArg.ID = uuid.New()
+	folderArg.CreatedAt = time.Now()
+	folderArg.UpdatedAt = folderArg.CreatedAt
+	folderArg.Name = "Reading"
+	folderArg.UserID = data.alice.ID
+	folder, err := s.db.CreateFolder(ctx, folderArg)
+	if err != nil {
+		t.Fatalf("create folder: %v", err)
+	}
+	var setFolder database.SetFeedFollowFolderParams
+	setFolder.UserID = data.alice.ID
+	setFolder.FeedID = data.blog.ID
+	setFolder.FolderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	if _, err := s.db.SetFeedFollowFolder(ctx, setFolder); err != nil {
+		t.Fatalf("set folder: %v", err)
+	}
+	var setTitle database.SetFeedFollowTitleParams
+	setTitle.UserID = data.bob.ID
+	setTitle.FeedID = data.blog.ID
+	setTitle.Title = sql.NullString{String: "Alice's blog", Valid: true}
+	if _, err := s.db.SetFeedFollowTitle(ctx, setTitle); err != nil {
+		t.Fatalf("set title: %v", err)
+	}
+
+	if err := storeToken(ctx, s, data.alice, "laptop", tokenScopeAPI, "alice-token"); err != nil {
+		t.Fatalf("store token: %v", err)
+	}
+	if err := storeToken(ctx, s, data.bob, feverTokenName, tokenScopeFever, "bob-fever-key"); err != nil {
+		t.Fatalf("store token: %v", err)
+	}
+
+	data.posts = append(data.posts, addTestPosts(t, s, data.blog, 30*day, "https://example.com/old")...)
+	data.posts = append(data.posts, addTestPosts(t, s, data.blog, day, "https://example.com/a", "https://example.com/b")...)
+	data.posts = append(data.posts, addTestPosts(t, s, data.news, time.Hour, "https://news.example.com/1")...)
+	readTestPost(t, s, data.alice, data.posts[1])
+	readTestPost(t, s, data.bob, data.posts[2])
+	starTestPost(t, s, data.alice, data.posts[2])
+	starTestPost(t, s, data.bob, data.posts[3])
+
+	s.cfg.KeepPosts = 2
+	if _, err := pruneFeed(ctx, s, data.blog); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	s.cfg.KeepPosts = 0
+	return data
+}
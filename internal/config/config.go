@@ -2,9 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 )
 
 const configFileName = ".gatorconfig.json"
@@ -12,6 +14,22 @@ const configFileName = ".gatorconfig.json"
 type Config struct {
 	DB_url   string `json:"db_url"`
 	Username string `json:"current_user_name"`
+	// Token is the session token given out by login.  Only its hash is
+	// kept in the database.
+	Token string `json:"session_token,omitempty"`
+	// KeepDays and KeepPosts are how long agg and prune keep a feed's
+	// posts, for feeds without their own setting.  Zero keeps them
+	// forever.
+	KeepDays  int `json:"keep_days,omitempty"`
+	KeepPosts int `json:"keep_posts,omitempty"`
+	// HTTPTimeout and DBTimeout are how long one feed fetch or one
+	// database query may take, as a duration like "30s".  Empty uses the
+	// default, and "0" waits forever.
+	HTTPTimeout string `json:"http_timeout,omitempty"`
+	DBTimeout   string `json:"db_timeout,omitempty"`
+	// OpenRegistration lets anyone create an account with POST
+	// /api/users.  Otherwise only admins can.
+	OpenRegistration bool `json:"open_registration,omitempty"`
 }
 
 func getConfigFilePath() (string, error) {
@@ -35,7 +53,9 @@ func write(cfg Config) error {
 		fmt.Println("ERROR: Could not marshal config file into json")
 		return err
 	}
-	err = os.WriteFile(configFilePath, configFile, 0666)
+	// The config holds a session token.  WriteFile would keep the mode
+	// of a file that already exists, so a new 0600 file replaces it.
+	err = writePrivateFile(configFilePath, configFile)
 	if err != nil {
 		fmt.Printf("ERROR: Unable to write file to %v\n", configFilePath)
 		return err
@@ -44,26 +64,59 @@ func write(cfg Config) error {
 	return nil
 }
 
+// writePrivateFile writes data to a temporary file that only the user can
+// read, then renames it over path.
+func writePrivateFile(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
 func Read() Config {
+	config, err := Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// fmt.Println("Successfully read config file.")
+	return config
+}
+
+// Load reads the config file like Read, but returns an error instead of
+// exiting, for rereading it while gator runs.
+func Load() (Config, error) {
 	configFilePath, err := getConfigFilePath()
 	if err != nil {
-		log.Fatal("Unable to read config file.")
+		return Config{}, errors.New("Unable to read config file.")
 	}
 	configFile, err := os.ReadFile(configFilePath)
 	if err != nil {
-		log.Fatal("Config file not found.")
+		return Config{}, errors.New("Config file not found.")
 	}
 	var config Config
 	err = json.Unmarshal(configFile, &config)
 	if err != nil {
-		log.Fatal("Could not unmarshal json data.")
+		return Config{}, errors.New("Could not unmarshal json data.")
 	}
-	// fmt.Println("Successfully read config file.")
-	return config
+	return config, nil
 }
 
-func SetUser(current_user_name string, cfg Config) error {
+func SetUser(current_user_name string, token string, cfg Config) error {
 	cfg.Username = current_user_name
+	cfg.Token = token
 	err := write(cfg)
 	if err != nil {
 		return err
@@ -71,3 +124,10 @@ func SetUser(current_user_name string, cfg Config) error {
 	fmt.Printf("Logged in as %v\n", cfg.Username)
 	return nil
 }
+
+// ClearUser forgets the current user and their session token.
+func ClearUser(cfg Config) error {
+	cfg.Username = ""
+	cfg.Token = ""
+	return write(cfg)
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,10 +23,10 @@ WITH inserted_feed_follow AS (
 		$4,
 		$5
 		)
-		RETURNING id, created_at, updated_at, user_id, feed_id
+		RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title
 )
 SELECT
-	inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+	inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.folder_id, inserted_feed_follow.title,
 	feeds.name as feed_name,
 	users.name as user_name
 FROM inserted_feed_follow
@@ -49,6 +50,8 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -68,54 +71,63 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
 		&i.FeedName,
 		&i.UserName,
 	)
 	return i, err
 }
 
-const deleteFeedFollowByUserNameAndFeedUrl = `-- name: DeleteFeedFollowByUserNameAndFeedUrl :one
-WITH deleted AS (
+const deleteFeedFollow = `-- name: DeleteFeedFollow :execrows
 DELETE FROM feed_follows
-USING users, feeds
-WHERE users.id = feed_follows.user_id
-AND feeds.id = feed_follows.feed_id
-AND users.name = $1
-AND feeds.url = $2
-RETURNING 1
-)
-SELECT COUNT(*) FROM deleted
+WHERE user_id = $1
+AND feed_id = $2
 `
 
-type DeleteFeedFollowByUserNameAndFeedUrlParams struct {
-	UserName string
-	FeedUrl  string
+type DeleteFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
 }
 
-// @param user_name: string
-// @param feed_url: string
-func (q *Queries) DeleteFeedFollowByUserNameAndFeedUrl(ctx context.Context, arg DeleteFeedFollowByUserNameAndFeedUrlParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, deleteFeedFollowByUserNameAndFeedUrl, arg.UserName, arg.FeedUrl)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-	feeds.name AS feed_name,
-	users.name as user_name
+	feeds.id AS feed_id,
+	feeds.url AS feed_url,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	users.name as user_name,
+	folders.name AS folder_name,
+	feeds.num AS feed_num,
+	folders.num AS folder_num,
+	feeds.last_fetched_at AS last_fetched_at
 FROM feed_follows
 INNER JOIN feeds
 ON feeds.id = feed_follows.feed_id
 INNER JOIN users
 ON users.id = feed_follows.user_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
 WHERE users.name = $1
+ORDER BY folders.name NULLS FIRST, COALESCE(feed_follows.title, feeds.name)
 `
 
 type GetFeedFollowsForUserRow struct {
-	FeedName string
-	UserName string
+	FeedID        uuid.UUID
+	FeedUrl       string
+	FeedName      string
+	UserName      string
+	FolderName    sql.NullString
+	FeedNum       int64
+	FolderNum     sql.NullInt64
+	LastFetchedAt sql.NullTime
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
@@ -127,7 +139,61 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.FeedName, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.FeedID,
+			&i.FeedUrl,
+			&i.FeedName,
+			&i.UserName,
+			&i.FolderName,
+			&i.FeedNum,
+			&i.FolderNum,
+			&i.LastFetchedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowsForUserInFolder = `-- name: GetFeedFollowsForUserInFolder :many
+SELECT
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND feed_follows.folder_id = $2
+ORDER BY COALESCE(feed_follows.title, feeds.name)
+`
+
+type GetFeedFollowsForUserInFolderParams struct {
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+}
+
+type GetFeedFollowsForUserInFolderRow struct {
+	FeedName string
+	FeedUrl  string
+}
+
+func (q *Queries) GetFeedFollowsForUserInFolder(ctx context.Context, arg GetFeedFollowsForUserInFolderParams) ([]GetFeedFollowsForUserInFolderRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUserInFolder, arg.UserID, arg.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowsForUserInFolderRow
+	for rows.Next() {
+		var i GetFeedFollowsForUserInFolderRow
+		if err := rows.Scan(&i.FeedName, &i.FeedUrl); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -140,3 +206,45 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 	}
 	return items, nil
 }
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3, updated_at = NOW()
+WHERE user_id = $1
+AND feed_id = $2
+`
+
+type SetFeedFollowFolderParams struct {
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder, arg.UserID, arg.FeedID, arg.FolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowTitle = `-- name: SetFeedFollowTitle :execrows
+UPDATE feed_follows
+SET title = $3, updated_at = NOW()
+WHERE user_id = $1
+AND feed_id = $2
+`
+
+type SetFeedFollowTitleParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+	Title  sql.NullString
+}
+
+func (q *Queries) SetFeedFollowTitle(ctx context.Context, arg SetFeedFollowTitleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowTitle, arg.UserID, arg.FeedID, arg.Title)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
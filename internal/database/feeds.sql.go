@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ VALUES (
 	$5,
 	$6
 	)
-	RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+	RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts
 `
 
 type CreateFeedParams struct {
@@ -31,7 +32,7 @@ type CreateFeedParams struct {
 	UpdatedAt time.Time
 	Name      string
 	Url       string
-	UserID    uuid.UUID
+	UserID    uuid.NullUUID
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -52,10 +53,202 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Num,
+		&i.KeepDays,
+		&i.KeepPosts,
 	)
 	return i, err
 }
 
+const deleteAllFeeds = `-- name: DeleteAllFeeds :exec
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteAllFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllFeeds)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts FROM feeds
+ORDER BY name
+`
+
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Num,
+			&i.KeepDays,
+			&i.KeepPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeed = `-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeed(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Num,
+		&i.KeepDays,
+		&i.KeepPosts,
+	)
+	return i, err
+}
+
+const getFeedByUrl = `-- name: GetFeedByUrl :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts FROM feeds
+WHERE url = $1
+`
+
+func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Num,
+		&i.KeepDays,
+		&i.KeepPosts,
+	)
+	return i, err
+}
+
+const getFeedCatalog = `-- name: GetFeedCatalog :many
+SELECT
+	feeds.id AS id,
+	feeds.name AS name,
+	feeds.url AS url,
+	users.name AS user_name,
+	feeds.last_fetched_at AS last_fetched_at,
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS follower_count,
+	(SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS post_count,
+	(SELECT MAX(posts.published_at) FROM posts WHERE posts.feed_id = feeds.id) AS last_post_at,
+	EXISTS (
+		SELECT 1 FROM feed_follows
+		WHERE feed_follows.feed_id = feeds.id
+		AND feed_follows.user_id = $1
+	) AS followed
+FROM feeds
+LEFT JOIN users
+ON feeds.user_id = users.id
+ORDER BY feeds.name
+`
+
+type GetFeedCatalogRow struct {
+	ID            uuid.UUID
+	Name          string
+	Url           string
+	UserName      sql.NullString
+	LastFetchedAt sql.NullTime
+	FollowerCount int64
+	PostCount     int64
+	LastPostAt    sql.NullTime
+	Followed      bool
+}
+
+// @param user_id: uuid
+func (q *Queries) GetFeedCatalog(ctx context.Context, userID uuid.UUID) ([]GetFeedCatalogRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedCatalog, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedCatalogRow
+	for rows.Next() {
+		var i GetFeedCatalogRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.UserName,
+			&i.LastFetchedAt,
+			&i.FollowerCount,
+			&i.PostCount,
+			&i.LastPostAt,
+			&i.Followed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedDeleteImpact = `-- name: GetFeedDeleteImpact :one
+SELECT
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS follower_count,
+	(SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS post_count
+`
+
+type GetFeedDeleteImpactRow struct {
+	FollowerCount int64
+	PostCount     int64
+}
+
+func (q *Queries) GetFeedDeleteImpact(ctx context.Context, feedID uuid.UUID) (GetFeedDeleteImpactRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedDeleteImpact, feedID)
+	var i GetFeedDeleteImpactRow
+	err := row.Scan(&i.FollowerCount, &i.PostCount)
+	return i, err
+}
+
 const getFeedIDByUrl = `-- name: GetFeedIDByUrl :one
 SELECT id
 FROM feeds
@@ -70,16 +263,17 @@ func (q *Queries) GetFeedIDByUrl(ctx context.Context, url string) (uuid.UUID, er
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name AS name, feeds.url AS url, users.name AS user_name
+SELECT feeds.id AS id, feeds.name AS name, feeds.url AS url, users.name AS user_name
 FROM feeds 
-INNER JOIN users
+LEFT JOIN users
 ON feeds.user_id = users.id
 `
 
 type GetFeedsRow struct {
+	ID       uuid.UUID
 	Name     string
 	Url      string
-	UserName string
+	UserName sql.NullString
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -91,7 +285,12 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.UserName,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -133,3 +332,113 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const renameFeed = `-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts
+`
+
+type RenameFeedParams struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, renameFeed, arg.ID, arg.Name)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Num,
+		&i.KeepDays,
+		&i.KeepPosts,
+	)
+	return i, err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET keep_days = $2, keep_posts = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts
+`
+
+type SetFeedRetentionParams struct {
+	ID        uuid.UUID
+	KeepDays  sql.NullInt32
+	KeepPosts sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention, arg.ID, arg.KeepDays, arg.KeepPosts)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Num,
+		&i.KeepDays,
+		&i.KeepPosts,
+	)
+	return i, err
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = $1, updated_at = NOW()
+WHERE user_id = $2
+`
+
+type TransferFeedsParams struct {
+	NewUserID uuid.NullUUID
+	OldUserID uuid.NullUUID
+}
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.NewUserID, arg.OldUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :one
+UPDATE feeds
+SET url = $2, updated_at = NOW(), last_fetched_at = NULL
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts
+`
+
+type UpdateFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedUrl, arg.ID, arg.Url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Num,
+		&i.KeepDays,
+		&i.KeepPosts,
+	)
+	return i, err
+}
@@ -7,9 +7,11 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
@@ -23,17 +25,21 @@ INSERT INTO posts (
 	published_at,
 	feed_id
 )
-VALUES (
-	$1,
+SELECT
+	$1::uuid,
 	NOW(),
 	NOW(),
-	$2,
-	$3,
-	$4,
-	$5,
-	$6
-	)
-	RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+	$2::text,
+	$3::text,
+	$4::text,
+	$5::timestamp,
+	$6::uuid
+WHERE NOT EXISTS (
+	SELECT 1 FROM pruned_posts
+	WHERE pruned_posts.url = $3
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, num
 `
 
 type CreatePostParams struct {
@@ -45,6 +51,8 @@ type CreatePostParams struct {
 	FeedID      uuid.UUID
 }
 
+// A post whose url is already stored, or was pruned, is skipped, and
+// returns no row.
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
 	row := q.db.QueryRowContext(ctx, createPost,
 		arg.ID,
@@ -64,6 +72,168 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Num,
+	)
+	return i, err
+}
+
+const createPosts = `-- name: CreatePosts :many
+INSERT INTO posts (
+	id,
+	created_at,
+	updated_at,
+	title,
+	url,
+	description,
+	published_at,
+	feed_id
+)
+SELECT
+	new_posts.id,
+	NOW(),
+	NOW(),
+	new_posts.title,
+	new_posts.url,
+	new_posts.description,
+	new_posts.published_at,
+	$1
+FROM unnest(
+	$2::uuid[],
+	$3::text[],
+	$4::text[],
+	$5::text[],
+	$6::timestamp[]
+) AS new_posts(id, title, url, description, published_at)
+WHERE NOT EXISTS (
+	SELECT 1 FROM pruned_posts
+	WHERE pruned_posts.url = new_posts.url
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, num
+`
+
+type CreatePostsParams struct {
+	FeedID       uuid.UUID
+	Ids          []uuid.UUID
+	Titles       []string
+	Urls         []string
+	Descriptions []string
+	PublishedAts []time.Time
+}
+
+// Stores a feed's posts in one statement.  Posts whose url is already
+// stored, or was pruned, are skipped, so only the new posts are returned.
+func (q *Queries) CreatePosts(ctx context.Context, arg CreatePostsParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, createPosts,
+		arg.FeedID,
+		pq.Array(arg.Ids),
+		pq.Array(arg.Titles),
+		pq.Array(arg.Urls),
+		pq.Array(arg.Descriptions),
+		pq.Array(arg.PublishedAts),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Num,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, num FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Num,
+	)
+	return i, err
+}
+
+const getPostByNum = `-- name: GetPostByNum :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, num FROM posts
+WHERE num = $1
+`
+
+func (q *Queries) GetPostByNum(ctx context.Context, num int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByNum, num)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Num,
+	)
+	return i, err
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.num FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE posts.id = $1
+AND feed_follows.user_id = $2
+`
+
+type GetPostForUserParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// Returns a post only if it is in a feed the user follows.
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.ID, arg.UserID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Num,
 	)
 	return i, err
 }
@@ -77,23 +247,56 @@ SELECT
 	posts.url AS url,
 	posts.description AS description,
 	posts.published_at AS published_at,
-	posts.feed_id AS feed_id
+	posts.feed_id AS feed_id,
+	posts.num AS num,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url,
+	folders.name AS folder_name,
+	post_reads.read_at AS read_at,
+	post_stars.starred_at AS starred_at
 FROM posts
 INNER JOIN feed_follows
 ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
 ORDER BY posts.published_at DESC
 `
 
-func (q *Queries) GetPostsForUser(ctx context.Context, userID uuid.UUID) ([]Post, error) {
+type GetPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Num         int64
+	FeedName    string
+	FeedUrl     string
+	FolderName  sql.NullString
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+func (q *Queries) GetPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetPostsForUserRow, error) {
 	rows, err := q.db.QueryContext(ctx, getPostsForUser, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -103,6 +306,12 @@ func (q *Queries) GetPostsForUser(ctx context.Context, userID uuid.UUID) ([]Post
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Num,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FolderName,
+			&i.ReadAt,
+			&i.StarredAt,
 		); err != nil {
 			return nil, err
 		}
@@ -116,3 +325,154 @@ func (q *Queries) GetPostsForUser(ctx context.Context, userID uuid.UUID) ([]Post
 	}
 	return items, nil
 }
+
+const getPostsForUserInFolder = `-- name: GetPostsForUserInFolder :many
+SELECT
+	posts.id AS id,
+	posts.created_at AS created_at,
+	posts.updated_at AS updated_at,
+	posts.title AS title,
+	posts.url AS url,
+	posts.description AS description,
+	posts.published_at AS published_at,
+	posts.feed_id AS feed_id,
+	posts.num AS num,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url,
+	folders.name AS folder_name,
+	post_reads.read_at AS read_at,
+	post_stars.starred_at AS starred_at
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND feed_follows.folder_id = $2
+ORDER BY posts.published_at DESC
+`
+
+type GetPostsForUserInFolderParams struct {
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+}
+
+type GetPostsForUserInFolderRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Num         int64
+	FeedName    string
+	FeedUrl     string
+	FolderName  sql.NullString
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+func (q *Queries) GetPostsForUserInFolder(ctx context.Context, arg GetPostsForUserInFolderParams) ([]GetPostsForUserInFolderRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserInFolder, arg.UserID, arg.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserInFolderRow
+	for rows.Next() {
+		var i GetPostsForUserInFolderRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Num,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FolderName,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const prunePosts = `-- name: PrunePosts :execrows
+WITH pruned AS (
+	INSERT INTO pruned_posts (url, feed_id, pruned_at)
+	SELECT posts.url, posts.feed_id, NOW()
+	FROM posts
+	WHERE posts.feed_id = $1
+	AND NOT EXISTS (
+		SELECT 1 FROM post_stars
+		WHERE post_stars.post_id = posts.id
+	)
+	AND (
+		posts.published_at < $2
+		OR posts.id NOT IN (
+			SELECT newest.id FROM posts AS newest
+			WHERE newest.feed_id = $1
+			ORDER BY newest.published_at DESC
+			LIMIT $3
+		)
+	)
+	ON CONFLICT (url) DO NOTHING
+)
+DELETE FROM posts
+WHERE posts.feed_id = $1
+AND NOT EXISTS (
+	SELECT 1 FROM post_stars
+	WHERE post_stars.post_id = posts.id
+)
+AND (
+	posts.published_at < $2
+	OR posts.id NOT IN (
+		SELECT newest.id FROM posts AS newest
+		WHERE newest.feed_id = $1
+		ORDER BY newest.published_at DESC
+		LIMIT $3
+	)
+)
+`
+
+type PrunePostsParams struct {
+	FeedID          uuid.UUID
+	PublishedBefore sql.NullTime
+	KeepPosts       sql.NullInt32
+}
+
+// Deletes a feed's posts that were published before published_before, or
+// are not among its newest keep_posts, and remembers their urls in
+// pruned_posts.  A NULL limit is no limit.  Starred posts are always kept.
+// Both statements see the posts as they were before either ran, so they
+// pick the same posts, and the rows affected are the DELETE's.
+func (q *Queries) PrunePosts(ctx context.Context, arg PrunePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, prunePosts, arg.FeedID, arg.PublishedBefore, arg.KeepPosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
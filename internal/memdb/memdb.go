@@ -0,0 +1,1666 @@
+// Package memdb is a database.Querier that keeps everything in memory.
+// It is for tests and dry runs, and for trying gator out without setting
+// up a database; nothing is saved when the process exits.
+//
+// Each query behaves like its SQL in sql/queries, including unique
+// constraints and the cascades in sql/schema, so code that works against
+// memdb works against Postgres.
+package memdb
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+// UniqueViolation is returned when a write would break a unique
+// constraint.  Constraints are named the way Postgres names them, like
+// "posts_url_key".
+type UniqueViolation struct {
+	Constraint string
+}
+
+func (e *UniqueViolation) Error() string {
+	return fmt.Sprintf("duplicate key value violates unique constraint %q", e.Constraint)
+}
+
+type postKey struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+type DB struct {
+	// txMu is held for the whole of a WithTx, so transactions run one at
+	// a time.  mu is held by each query.
+	txMu       sync.Mutex
+	mu         sync.Mutex
+	users      map[uuid.UUID]database.User
+	feeds      map[uuid.UUID]database.Feed
+	follows    map[uuid.UUID]database.FeedFollow
+	folders    map[uuid.UUID]database.Folder
+	posts      map[uuid.UUID]database.Post
+	reads      map[postKey]database.PostRead
+	stars      map[postKey]database.PostStar
+	pruned     map[string]database.PrunedPost
+	tokens     map[uuid.UUID]database.ApiToken
+	feedNum    int64
+	folderNum  int64
+	postNum    int64
+	lastChange time.Time
+}
+
+var _ database.Querier = (*DB)(nil)
+
+func New() *DB {
+	return &DB{
+		users:   make(map[uuid.UUID]database.User),
+		feeds:   make(map[uuid.UUID]database.Feed),
+		follows: make(map[uuid.UUID]database.FeedFollow),
+		folders: make(map[uuid.UUID]database.Folder),
+		posts:   make(map[uuid.UUID]database.Post),
+		reads:   make(map[postKey]database.PostRead),
+		stars:   make(map[postKey]database.PostStar),
+		pruned:  make(map[string]database.PrunedPost),
+		tokens:  make(map[uuid.UUID]database.ApiToken),
+	}
+}
+
+// WithTx runs fn against db, and undoes everything fn did if it returns
+// an error.  Transactions run one at a time, but unlike a real
+// transaction, fn's writes are seen by other goroutines before it returns.
+func (db *DB) WithTx(fn func(database.Querier) error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	db.mu.Lock()
+	saved := db.clone()
+	db.mu.Unlock()
+	err := fn(db)
+	if err != nil {
+		db.mu.Lock()
+		db.restore(saved)
+		db.mu.Unlock()
+	}
+	return err
+}
+
+// clone copies the tables.  Rows are values, so copying the maps is
+// enough.
+func (db *DB) clone() *DB {
+	return &DB{
+		users:      maps.Clone(db.users),
+		feeds:      maps.Clone(db.feeds),
+		follows:    maps.Clone(db.follows),
+		folders:    maps.Clone(db.folders),
+		posts:      maps.Clone(db.posts),
+		reads:      maps.Clone(db.reads),
+		stars:      maps.Clone(db.stars),
+		pruned:     maps.Clone(db.pruned),
+		tokens:     maps.Clone(db.tokens),
+		feedNum:    db.feedNum,
+		folderNum:  db.folderNum,
+		postNum:    db.postNum,
+		lastChange: db.lastChange,
+	}
+}
+
+func (db *DB) restore(saved *DB) {
+	db.users = saved.users
+	db.feeds = saved.feeds
+	db.follows = saved.follows
+	db.folders = saved.folders
+	db.posts = saved.posts
+	db.reads = saved.reads
+	db.stars = saved.stars
+	db.pruned = saved.pruned
+	db.tokens = saved.tokens
+	db.feedNum = saved.feedNum
+	db.folderNum = saved.folderNum
+	db.postNum = saved.postNum
+}
+
+// now stands in for NOW().  It never goes backwards, so rows touched one
+// after another sort in that order.
+func (db *DB) now() time.Time {
+	now := time.Now().UTC()
+	if !now.After(db.lastChange) {
+		now = db.lastChange.Add(time.Microsecond)
+	}
+	db.lastChange = now
+	return now
+}
+
+func sorted[K comparable, T any](m map[K]T, compare func(a, b T) int) []T {
+	items := make([]T, 0, len(m))
+	for _, item := range m {
+		items = append(items, item)
+	}
+	slices.SortFunc(items, compare)
+	return items
+}
+
+func (db *DB) userByName(name string) (database.User, bool) {
+	for _, user := range db.users {
+		if user.Name == name {
+			return user, true
+		}
+	}
+	return database.User{}, false
+}
+
+func (db *DB) feedByUrl(url string) (database.Feed, bool) {
+	for _, feed := range db.feeds {
+		if feed.Url == url {
+			return feed, true
+		}
+	}
+	return database.Feed{}, false
+}
+
+func (db *DB) checkFeedUnique(feed database.Feed) error {
+	for _, other := range db.feeds {
+		switch {
+		case other.ID == feed.ID:
+			continue
+		case other.Name == feed.Name:
+			return &UniqueViolation{Constraint: "feeds_name_key"}
+		case other.Url == feed.Url:
+			return &UniqueViolation{Constraint: "feeds_url_key"}
+		}
+	}
+	return nil
+}
+
+func (db *DB) checkRole(role string) error {
+	if role != "admin" && role != "user" {
+		return fmt.Errorf("new row for relation \"users\" violates check constraint \"valid_role\"")
+	}
+	return nil
+}
+
+// deletePost removes a post along with its reads and stars.
+func (db *DB) deletePost(id uuid.UUID) {
+	delete(db.posts, id)
+	for key := range db.reads {
+		if key.PostID == id {
+			delete(db.reads, key)
+		}
+	}
+	for key := range db.stars {
+		if key.PostID == id {
+			delete(db.stars, key)
+		}
+	}
+}
+
+// deleteFeed removes a feed along with its follows, posts and pruned
+// posts.
+func (db *DB) deleteFeed(id uuid.UUID) {
+	delete(db.feeds, id)
+	for url, pruned := range db.pruned {
+		if pruned.FeedID == id {
+			delete(db.pruned, url)
+		}
+	}
+	for followID, follow := range db.follows {
+		if follow.FeedID == id {
+			delete(db.follows, followID)
+		}
+	}
+	for postID, post := range db.posts {
+		if post.FeedID == id {
+			db.deletePost(postID)
+		}
+	}
+}
+
+// deleteUser removes a user and everything of theirs.  Feeds they added
+// are kept without an owner.
+func (db *DB) deleteUser(id uuid.UUID) {
+	delete(db.users, id)
+	for feedID, feed := range db.feeds {
+		if feed.UserID.Valid && feed.UserID.UUID == id {
+			feed.UserID = uuid.NullUUID{}
+			db.feeds[feedID] = feed
+		}
+	}
+	for followID, follow := range db.follows {
+		if follow.UserID == id {
+			delete(db.follows, followID)
+		}
+	}
+	for folderID, folder := range db.folders {
+		if folder.UserID == id {
+			delete(db.folders, folderID)
+		}
+	}
+	for key := range db.reads {
+		if key.UserID == id {
+			delete(db.reads, key)
+		}
+	}
+	for key := range db.stars {
+		if key.UserID == id {
+			delete(db.stars, key)
+		}
+	}
+	for tokenID, token := range db.tokens {
+		if token.UserID == id {
+			delete(db.tokens, tokenID)
+		}
+	}
+}
+
+func (db *DB) ClearFeedFetchTimes(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for id, feed := range db.feeds {
+		if feed.LastFetchedAt.Valid {
+			feed.LastFetchedAt = sql.NullTime{}
+			db.feeds[id] = feed
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) CountAdmins(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for _, user := range db.users {
+		if user.Role == "admin" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) CountUsers(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return int64(len(db.users)), nil
+}
+
+func (db *DB) CreateAPIToken(ctx context.Context, arg database.CreateAPITokenParams) (database.ApiToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.tokens[arg.ID]; ok {
+		return database.ApiToken{}, &UniqueViolation{Constraint: "api_tokens_pkey"}
+	}
+	if _, ok := db.users[arg.UserID]; !ok {
+		return database.ApiToken{}, fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	for _, token := range db.tokens {
+		if token.TokenHash == arg.TokenHash {
+			return database.ApiToken{}, &UniqueViolation{Constraint: "api_tokens_token_hash_key"}
+		}
+	}
+	token := database.ApiToken{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		TokenHash: arg.TokenHash,
+		Scope:     arg.Scope,
+	}
+	db.tokens[token.ID] = token
+	return token, nil
+}
+
+func (db *DB) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.feeds[arg.ID]; ok {
+		return database.Feed{}, &UniqueViolation{Constraint: "feeds_pkey"}
+	}
+	if _, ok := db.users[arg.UserID.UUID]; arg.UserID.Valid && !ok {
+		return database.Feed{}, fmt.Errorf("user %v does not exist", arg.UserID.UUID)
+	}
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	if err := db.checkFeedUnique(feed); err != nil {
+		return database.Feed{}, err
+	}
+	db.feedNum++
+	feed.Num = db.feedNum
+	db.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (db *DB) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.follows[arg.ID]; ok {
+		return database.CreateFeedFollowRow{}, &UniqueViolation{Constraint: "feed_follows_pkey"}
+	}
+	user, ok := db.users[arg.UserID]
+	if !ok {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	feed, ok := db.feeds[arg.FeedID]
+	if !ok {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("feed %v does not exist", arg.FeedID)
+	}
+	for _, follow := range db.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return database.CreateFeedFollowRow{}, &UniqueViolation{Constraint: "unique_user_feed"}
+		}
+	}
+	follow := database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	db.follows[follow.ID] = follow
+	return database.CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		FolderID:  follow.FolderID,
+		Title:     follow.Title,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (db *DB) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.folders[arg.ID]; ok {
+		return database.Folder{}, &UniqueViolation{Constraint: "folders_pkey"}
+	}
+	if _, ok := db.users[arg.UserID]; !ok {
+		return database.Folder{}, fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	for _, folder := range db.folders {
+		if folder.UserID == arg.UserID && folder.Name == arg.Name {
+			return database.Folder{}, &UniqueViolation{Constraint: "unique_user_folder"}
+		}
+	}
+	db.folderNum++
+	folder := database.Folder{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		UserID:    arg.UserID,
+		Num:       db.folderNum,
+	}
+	db.folders[folder.ID] = folder
+	return folder, nil
+}
+
+// CreatePost skips a post whose url is already stored, and returns
+// sql.ErrNoRows.
+func (db *DB) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.feeds[arg.FeedID]; !ok {
+		return database.Post{}, fmt.Errorf("feed %v does not exist", arg.FeedID)
+	}
+	post, ok, err := db.insertPost(arg)
+	if err == nil && !ok {
+		err = sql.ErrNoRows
+	}
+	return post, err
+}
+
+func (db *DB) CreatePosts(ctx context.Context, arg database.CreatePostsParams) ([]database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.feeds[arg.FeedID]; !ok {
+		return nil, fmt.Errorf("feed %v does not exist", arg.FeedID)
+	}
+	n := len(arg.Ids)
+	if len(arg.Titles) != n || len(arg.Urls) != n || len(arg.Descriptions) != n || len(arg.PublishedAts) != n {
+		return nil, fmt.Errorf("CreatePosts: arrays have different lengths")
+	}
+	var posts []database.Post
+	for i, id := range arg.Ids {
+		var postArg database.CreatePostParams
+		postArg.ID = id
+		postArg.Title = arg.Titles[i]
+		postArg.Url = arg.Urls[i]
+		postArg.Description = arg.Descriptions[i]
+		postArg.PublishedAt = arg.PublishedAts[i]
+		postArg.FeedID = arg.FeedID
+		post, ok, err := db.insertPost(postArg)
+		if err != nil {
+			// One statement, so none of the posts are kept.
+			for _, inserted := range posts {
+				delete(db.posts, inserted.ID)
+			}
+			return nil, err
+		}
+		if ok {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+// insertPost stores a post unless its url is already stored, or was
+// pruned.
+func (db *DB) insertPost(arg database.CreatePostParams) (database.Post, bool, error) {
+	if _, ok := db.posts[arg.ID]; ok {
+		return database.Post{}, false, &UniqueViolation{Constraint: "posts_pkey"}
+	}
+	if _, ok := db.pruned[arg.Url]; ok {
+		return database.Post{}, false, nil
+	}
+	for _, post := range db.posts {
+		if post.Url == arg.Url {
+			return database.Post{}, false, nil
+		}
+	}
+	now := db.now()
+	db.postNum++
+	post := database.Post{
+		ID:          arg.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Num:         db.postNum,
+	}
+	db.posts[post.ID] = post
+	return post, true, nil
+}
+
+func (db *DB) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.ID]; ok {
+		return database.User{}, &UniqueViolation{Constraint: "users_pkey"}
+	}
+	if _, ok := db.userByName(arg.Name); ok {
+		return database.User{}, &UniqueViolation{Constraint: "users_name_key"}
+	}
+	if err := db.checkRole(arg.Role); err != nil {
+		return database.User{}, err
+	}
+	user := database.User{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		UpdatedAt:    arg.UpdatedAt,
+		Name:         arg.Name,
+		PasswordHash: arg.PasswordHash,
+		Role:         arg.Role,
+	}
+	db.users[user.ID] = user
+	return user, nil
+}
+
+func (db *DB) DeleteAPIToken(ctx context.Context, arg database.DeleteAPITokenParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	token, ok := db.tokens[arg.ID]
+	if !ok || token.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(db.tokens, arg.ID)
+	return 1, nil
+}
+
+func (db *DB) DeleteAPITokenByHash(ctx context.Context, tokenHash string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for id, token := range db.tokens {
+		if token.TokenHash == tokenHash {
+			delete(db.tokens, id)
+		}
+	}
+	return nil
+}
+
+func (db *DB) DeleteAPITokensByName(ctx context.Context, arg database.DeleteAPITokensByNameParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for id, token := range db.tokens {
+		if token.UserID == arg.UserID && token.Name == arg.Name {
+			delete(db.tokens, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) DeleteAllFeeds(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for id := range db.feeds {
+		db.deleteFeed(id)
+	}
+	return nil
+}
+
+func (db *DB) DeleteAllPosts(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	count := int64(len(db.posts))
+	for id := range db.posts {
+		db.deletePost(id)
+	}
+	return count, nil
+}
+
+func (db *DB) DeleteAllPrunedPosts(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	count := int64(len(db.pruned))
+	clear(db.pruned)
+	return count, nil
+}
+
+func (db *DB) DeleteAllUsers(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for id := range db.users {
+		db.deleteUser(id)
+	}
+	return nil
+}
+
+func (db *DB) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.deleteFeed(id)
+	return nil
+}
+
+func (db *DB) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for id, follow := range db.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			delete(db.follows, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for id, follow := range db.follows {
+		if follow.UserID == userID {
+			delete(db.follows, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) DeleteFolder(ctx context.Context, arg database.DeleteFolderParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for id, folder := range db.folders {
+		if folder.UserID != arg.UserID || folder.Name != arg.Name {
+			continue
+		}
+		delete(db.folders, id)
+		count++
+		for followID, follow := range db.follows {
+			if follow.FolderID.Valid && follow.FolderID.UUID == id {
+				follow.FolderID = uuid.NullUUID{}
+				db.follows[followID] = follow
+			}
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) DeleteFoldersForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for id, folder := range db.folders {
+		if folder.UserID != userID {
+			continue
+		}
+		delete(db.folders, id)
+		count++
+		for followID, follow := range db.follows {
+			if follow.FolderID.Valid && follow.FolderID.UUID == id {
+				follow.FolderID = uuid.NullUUID{}
+				db.follows[followID] = follow
+			}
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) DeletePostReadsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for key := range db.reads {
+		if key.UserID == userID {
+			delete(db.reads, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) DeletePostStarsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for key := range db.stars {
+		if key.UserID == userID {
+			delete(db.stars, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[id]; !ok {
+		return 0, nil
+	}
+	db.deleteUser(id)
+	return 1, nil
+}
+
+func (db *DB) GetAPITokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var tokens []database.ApiToken
+	for _, token := range sorted(db.tokens, func(a, b database.ApiToken) int { return a.CreatedAt.Compare(b.CreatedAt) }) {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (db *DB) GetAllAPITokens(ctx context.Context) ([]database.ApiToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.tokens, func(a, b database.ApiToken) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID.String(), b.ID.String()))
+	}), nil
+}
+
+func (db *DB) GetAllFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.follows, func(a, b database.FeedFollow) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID.String(), b.ID.String()))
+	}), nil
+}
+
+func (db *DB) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.feeds, func(a, b database.Feed) int { return cmp.Compare(a.Name, b.Name) }), nil
+}
+
+func (db *DB) GetAllFolders(ctx context.Context) ([]database.Folder, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.folders, func(a, b database.Folder) int { return cmp.Compare(a.Num, b.Num) }), nil
+}
+
+func (db *DB) GetAllPostReads(ctx context.Context) ([]database.PostRead, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.reads, func(a, b database.PostRead) int {
+		return cmp.Or(a.ReadAt.Compare(b.ReadAt), cmp.Compare(a.UserID.String(), b.UserID.String()), cmp.Compare(a.PostID.String(), b.PostID.String()))
+	}), nil
+}
+
+func (db *DB) GetAllPostStars(ctx context.Context) ([]database.PostStar, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.stars, func(a, b database.PostStar) int {
+		return cmp.Or(a.StarredAt.Compare(b.StarredAt), cmp.Compare(a.UserID.String(), b.UserID.String()), cmp.Compare(a.PostID.String(), b.PostID.String()))
+	}), nil
+}
+
+func (db *DB) GetAllPosts(ctx context.Context) ([]database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.posts, func(a, b database.Post) int { return cmp.Compare(a.Num, b.Num) }), nil
+}
+
+func (db *DB) GetAllPrunedPosts(ctx context.Context) ([]database.PrunedPost, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.pruned, func(a, b database.PrunedPost) int {
+		return cmp.Or(a.PrunedAt.Compare(b.PrunedAt), cmp.Compare(a.Url, b.Url))
+	}), nil
+}
+
+// GetDatabaseSize is always 0, since nothing is stored on disk.
+func (db *DB) GetDatabaseSize(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (db *DB) GetFeed(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feed, ok := db.feeds[id]
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (db *DB) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feed, ok := db.feedByUrl(url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (db *DB) GetFeedCatalog(ctx context.Context, userID uuid.UUID) ([]database.GetFeedCatalogRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var rows []database.GetFeedCatalogRow
+	for _, feed := range sorted(db.feeds, func(a, b database.Feed) int { return cmp.Compare(a.Name, b.Name) }) {
+		row := database.GetFeedCatalogRow{
+			ID:            feed.ID,
+			Name:          feed.Name,
+			Url:           feed.Url,
+			LastFetchedAt: feed.LastFetchedAt,
+		}
+		if user, ok := db.users[feed.UserID.UUID]; feed.UserID.Valid && ok {
+			row.UserName = sql.NullString{String: user.Name, Valid: true}
+		}
+		for _, follow := range db.follows {
+			if follow.FeedID == feed.ID {
+				row.FollowerCount++
+				row.Followed = row.Followed || follow.UserID == userID
+			}
+		}
+		for _, post := range db.posts {
+			if post.FeedID != feed.ID {
+				continue
+			}
+			row.PostCount++
+			if !row.LastPostAt.Valid || post.PublishedAt.After(row.LastPostAt.Time) {
+				row.LastPostAt = sql.NullTime{Time: post.PublishedAt, Valid: true}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (db *DB) GetFeedDeleteImpact(ctx context.Context, feedID uuid.UUID) (database.GetFeedDeleteImpactRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var row database.GetFeedDeleteImpactRow
+	for _, follow := range db.follows {
+		if follow.FeedID == feedID {
+			row.FollowerCount++
+		}
+	}
+	for _, post := range db.posts {
+		if post.FeedID == feedID {
+			row.PostCount++
+		}
+	}
+	return row, nil
+}
+
+// followsForUser returns a user's follows with their feeds and folders,
+// sorted by folder name with unfiled feeds first, then by the feed's name
+// as the user sees it.
+func (db *DB) followsForUser(userID uuid.UUID) []database.FeedFollow {
+	var follows []database.FeedFollow
+	for _, follow := range db.follows {
+		if follow.UserID == userID {
+			follows = append(follows, follow)
+		}
+	}
+	slices.SortFunc(follows, func(a, b database.FeedFollow) int {
+		aFolder, bFolder := db.folders[a.FolderID.UUID], db.folders[b.FolderID.UUID]
+		if a.FolderID.Valid != b.FolderID.Valid {
+			if !a.FolderID.Valid {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(aFolder.Name, bFolder.Name); a.FolderID.Valid && c != 0 {
+			return c
+		}
+		return cmp.Compare(feedName(a, db.feeds[a.FeedID]), feedName(b, db.feeds[b.FeedID]))
+	})
+	return follows
+}
+
+func feedName(follow database.FeedFollow, feed database.Feed) string {
+	if follow.Title.Valid {
+		return follow.Title.String
+	}
+	return feed.Name
+}
+
+func (db *DB) GetFeedFollowsForUser(ctx context.Context, name string) ([]database.GetFeedFollowsForUserRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, ok := db.userByName(name)
+	if !ok {
+		return nil, nil
+	}
+	var rows []database.GetFeedFollowsForUserRow
+	for _, follow := range db.followsForUser(user.ID) {
+		feed := db.feeds[follow.FeedID]
+		row := database.GetFeedFollowsForUserRow{
+			FeedID:        feed.ID,
+			FeedUrl:       feed.Url,
+			FeedName:      feedName(follow, feed),
+			UserName:      user.Name,
+			FeedNum:       feed.Num,
+			LastFetchedAt: feed.LastFetchedAt,
+		}
+		if folder, ok := db.folders[follow.FolderID.UUID]; follow.FolderID.Valid && ok {
+			row.FolderName = sql.NullString{String: folder.Name, Valid: true}
+			row.FolderNum = sql.NullInt64{Int64: folder.Num, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (db *DB) GetFeedFollowsForUserInFolder(ctx context.Context, arg database.GetFeedFollowsForUserInFolderParams) ([]database.GetFeedFollowsForUserInFolderRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var rows []database.GetFeedFollowsForUserInFolderRow
+	for _, follow := range db.followsForUser(arg.UserID) {
+		if !arg.FolderID.Valid || follow.FolderID != arg.FolderID {
+			continue
+		}
+		feed := db.feeds[follow.FeedID]
+		rows = append(rows, database.GetFeedFollowsForUserInFolderRow{FeedName: feedName(follow, feed), FeedUrl: feed.Url})
+	}
+	return rows, nil
+}
+
+func (db *DB) GetFeedIDByUrl(ctx context.Context, url string) (uuid.UUID, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feed, ok := db.feedByUrl(url)
+	if !ok {
+		return uuid.UUID{}, sql.ErrNoRows
+	}
+	return feed.ID, nil
+}
+
+func (db *DB) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var rows []database.GetFeedsRow
+	for _, feed := range sorted(db.feeds, func(a, b database.Feed) int { return a.CreatedAt.Compare(b.CreatedAt) }) {
+		row := database.GetFeedsRow{ID: feed.ID, Name: feed.Name, Url: feed.Url}
+		if user, ok := db.users[feed.UserID.UUID]; feed.UserID.Valid && ok {
+			row.UserName = sql.NullString{String: user.Name, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (db *DB) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var folders []database.Folder
+	for _, folder := range sorted(db.folders, func(a, b database.Folder) int { return cmp.Compare(a.Name, b.Name) }) {
+		if folder.UserID == userID {
+			folders = append(folders, folder)
+		}
+	}
+	return folders, nil
+}
+
+func (db *DB) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, folder := range db.folders {
+		if folder.UserID == arg.UserID && folder.Name == arg.Name {
+			return folder, nil
+		}
+	}
+	return database.Folder{}, sql.ErrNoRows
+}
+
+func (db *DB) GetNextFeedToFetch(ctx context.Context) (database.GetNextFeedToFetchRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feeds := sorted(db.feeds, func(a, b database.Feed) int {
+		if a.LastFetchedAt.Valid != b.LastFetchedAt.Valid {
+			if !a.LastFetchedAt.Valid {
+				return -1
+			}
+			return 1
+		}
+		return a.LastFetchedAt.Time.Compare(b.LastFetchedAt.Time)
+	})
+	if len(feeds) == 0 {
+		return database.GetNextFeedToFetchRow{}, sql.ErrNoRows
+	}
+	return database.GetNextFeedToFetchRow{ID: feeds[0].ID, Url: feeds[0].Url}, nil
+}
+
+func (db *DB) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	post, ok := db.posts[id]
+	if !ok {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return post, nil
+}
+
+func (db *DB) GetPostByNum(ctx context.Context, num int64) (database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, post := range db.posts {
+		if post.Num == num {
+			return post, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (db *DB) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	post, ok := db.posts[arg.ID]
+	if !ok {
+		return database.Post{}, sql.ErrNoRows
+	}
+	for _, follow := range db.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == post.FeedID {
+			return post, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+// postsForUser returns the posts of every feed a user follows, newest
+// first, optionally only those in one folder.
+func (db *DB) postsForUser(userID uuid.UUID, inFolder func(database.FeedFollow) bool) []database.GetPostsForUserRow {
+	follows := make(map[uuid.UUID]database.FeedFollow)
+	for _, follow := range db.follows {
+		if follow.UserID == userID && inFolder(follow) {
+			follows[follow.FeedID] = follow
+		}
+	}
+	var rows []database.GetPostsForUserRow
+	for _, post := range sorted(db.posts, func(a, b database.Post) int { return b.PublishedAt.Compare(a.PublishedAt) }) {
+		follow, ok := follows[post.FeedID]
+		if !ok {
+			continue
+		}
+		feed := db.feeds[post.FeedID]
+		row := database.GetPostsForUserRow{
+			ID:          post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Num:         post.Num,
+			FeedName:    feedName(follow, feed),
+			FeedUrl:     feed.Url,
+		}
+		if folder, ok := db.folders[follow.FolderID.UUID]; follow.FolderID.Valid && ok {
+			row.FolderName = sql.NullString{String: folder.Name, Valid: true}
+		}
+		if read, ok := db.reads[postKey{UserID: userID, PostID: post.ID}]; ok {
+			row.ReadAt = sql.NullTime{Time: read.ReadAt, Valid: true}
+		}
+		if star, ok := db.stars[postKey{UserID: userID, PostID: post.ID}]; ok {
+			row.StarredAt = sql.NullTime{Time: star.StarredAt, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (db *DB) GetPostCountsByDay(ctx context.Context, since time.Time) ([]database.GetPostCountsByDayRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	type dayKey struct {
+		FeedID uuid.UUID
+		Day    string
+	}
+	counts := make(map[dayKey]int64)
+	for _, post := range db.posts {
+		if post.PublishedAt.Before(since) {
+			continue
+		}
+		counts[dayKey{FeedID: post.FeedID, Day: post.PublishedAt.UTC().Format(time.DateOnly)}]++
+	}
+	var rows []database.GetPostCountsByDayRow
+	for key, count := range counts {
+		rows = append(rows, database.GetPostCountsByDayRow{
+			FeedID:    key.FeedID,
+			FeedName:  db.feeds[key.FeedID].Name,
+			Day:       key.Day,
+			PostCount: count,
+		})
+	}
+	slices.SortFunc(rows, func(a, b database.GetPostCountsByDayRow) int {
+		return cmp.Or(cmp.Compare(a.FeedName, b.FeedName), cmp.Compare(a.Day, b.Day))
+	})
+	return rows, nil
+}
+
+func (db *DB) GetPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetPostsForUserRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.postsForUser(userID, func(database.FeedFollow) bool { return true }), nil
+}
+
+func (db *DB) GetPostsForUserInFolder(ctx context.Context, arg database.GetPostsForUserInFolderParams) ([]database.GetPostsForUserInFolderRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var rows []database.GetPostsForUserInFolderRow
+	for _, row := range db.postsForUser(arg.UserID, func(follow database.FeedFollow) bool {
+		return arg.FolderID.Valid && follow.FolderID == arg.FolderID
+	}) {
+		rows = append(rows, database.GetPostsForUserInFolderRow(row))
+	}
+	return rows, nil
+}
+
+func (db *DB) GetReadRatios(ctx context.Context) ([]database.GetReadRatiosRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var rows []database.GetReadRatiosRow
+	for _, user := range sorted(db.users, func(a, b database.User) int { return cmp.Compare(a.Name, b.Name) }) {
+		row := database.GetReadRatiosRow{UserName: user.Name}
+		for _, follow := range db.follows {
+			if follow.UserID != user.ID {
+				continue
+			}
+			for _, post := range db.posts {
+				if post.FeedID != follow.FeedID {
+					continue
+				}
+				row.PostCount++
+				if _, ok := db.reads[postKey{UserID: user.ID, PostID: post.ID}]; ok {
+					row.ReadCount++
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (db *DB) GetResetImpact(ctx context.Context) (database.GetResetImpactRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var impact database.GetResetImpactRow
+	impact.UserCount = int64(len(db.users))
+	impact.TokenCount = int64(len(db.tokens))
+	impact.FeedCount = int64(len(db.feeds))
+	for _, feed := range db.feeds {
+		if feed.LastFetchedAt.Valid {
+			impact.FetchedFeedCount++
+		}
+	}
+	impact.FollowCount = int64(len(db.follows))
+	impact.FolderCount = int64(len(db.folders))
+	impact.PostCount = int64(len(db.posts))
+	impact.ReadCount = int64(len(db.reads))
+	impact.StarCount = int64(len(db.stars))
+	impact.PrunedPostCount = int64(len(db.pruned))
+	return impact, nil
+}
+
+func (db *DB) GetUser(ctx context.Context, name string) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, ok := db.userByName(name)
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (db *DB) GetUserByTokenHash(ctx context.Context, arg database.GetUserByTokenHashParams) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, token := range db.tokens {
+		if token.TokenHash == arg.TokenHash && token.Scope == arg.Scope {
+			return db.users[token.UserID], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (db *DB) GetUserResetImpact(ctx context.Context, userID uuid.UUID) (database.GetUserResetImpactRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var impact database.GetUserResetImpactRow
+	for _, follow := range db.follows {
+		if follow.UserID == userID {
+			impact.FollowCount++
+		}
+	}
+	for _, folder := range db.folders {
+		if folder.UserID == userID {
+			impact.FolderCount++
+		}
+	}
+	for key := range db.reads {
+		if key.UserID == userID {
+			impact.ReadCount++
+		}
+	}
+	for key := range db.stars {
+		if key.UserID == userID {
+			impact.StarCount++
+		}
+	}
+	return impact, nil
+}
+
+func (db *DB) GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var row database.GetUserStatsRow
+	for _, feed := range db.feeds {
+		if feed.UserID.Valid && feed.UserID.UUID == userID {
+			row.FeedsAdded++
+		}
+	}
+	for _, follow := range db.follows {
+		if follow.UserID == userID {
+			row.FollowCount++
+		}
+	}
+	for _, folder := range db.folders {
+		if folder.UserID == userID {
+			row.FolderCount++
+		}
+	}
+	for _, post := range db.postsForUser(userID, func(database.FeedFollow) bool { return true }) {
+		row.PostCount++
+		if !post.ReadAt.Valid {
+			row.UnreadCount++
+		}
+	}
+	for key := range db.stars {
+		if key.UserID == userID {
+			row.StarredCount++
+		}
+	}
+	for _, token := range db.tokens {
+		if token.UserID == userID {
+			row.TokenCount++
+		}
+	}
+	return row, nil
+}
+
+func (db *DB) GetUsers(ctx context.Context) ([]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var names []string
+	for _, user := range sorted(db.users, func(a, b database.User) int { return a.CreatedAt.Compare(b.CreatedAt) }) {
+		names = append(names, user.Name)
+	}
+	return names, nil
+}
+
+func (db *DB) ListUsers(ctx context.Context) ([]database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return sorted(db.users, func(a, b database.User) int { return cmp.Compare(a.Name, b.Name) }), nil
+}
+
+// LockUsers does nothing, since transactions already run one at a time.
+func (db *DB) LockUsers(ctx context.Context) error {
+	return nil
+}
+
+func (db *DB) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feed, ok := db.feeds[id]
+	if !ok {
+		return nil
+	}
+	feed.UpdatedAt = db.now()
+	feed.LastFetchedAt = sql.NullTime{Time: feed.UpdatedAt, Valid: true}
+	db.feeds[id] = feed
+	return nil
+}
+
+func (db *DB) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.UserID]; !ok {
+		return fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	if _, ok := db.posts[arg.PostID]; !ok {
+		return fmt.Errorf("post %v does not exist", arg.PostID)
+	}
+	key := postKey{UserID: arg.UserID, PostID: arg.PostID}
+	if _, ok := db.reads[key]; !ok {
+		db.reads[key] = database.PostRead{UserID: arg.UserID, PostID: arg.PostID, ReadAt: arg.ReadAt}
+	}
+	return nil
+}
+
+func (db *DB) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.reads, postKey{UserID: arg.UserID, PostID: arg.PostID})
+	return nil
+}
+
+func (db *DB) PrunePosts(ctx context.Context, arg database.PrunePostsParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var posts []database.Post
+	for _, post := range db.posts {
+		if post.FeedID == arg.FeedID {
+			posts = append(posts, post)
+		}
+	}
+	slices.SortFunc(posts, func(a, b database.Post) int {
+		return b.PublishedAt.Compare(a.PublishedAt)
+	})
+	starred := make(map[uuid.UUID]bool)
+	for key := range db.stars {
+		starred[key.PostID] = true
+	}
+	now := db.now()
+	var count int64
+	for i, post := range posts {
+		if starred[post.ID] {
+			continue
+		}
+		tooOld := arg.PublishedBefore.Valid && post.PublishedAt.Before(arg.PublishedBefore.Time)
+		tooMany := arg.KeepPosts.Valid && i >= int(arg.KeepPosts.Int32)
+		if !tooOld && !tooMany {
+			continue
+		}
+		if _, ok := db.pruned[post.Url]; !ok {
+			db.pruned[post.Url] = database.PrunedPost{Url: post.Url, FeedID: post.FeedID, PrunedAt: now}
+		}
+		db.deletePost(post.ID)
+		count++
+	}
+	return count, nil
+}
+
+func (db *DB) RenameFeed(ctx context.Context, arg database.RenameFeedParams) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feed, ok := db.feeds[arg.ID]
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.Name = arg.Name
+	if err := db.checkFeedUnique(feed); err != nil {
+		return database.Feed{}, err
+	}
+	feed.UpdatedAt = db.now()
+	db.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (db *DB) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (database.Folder, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for id, folder := range db.folders {
+		if folder.UserID != arg.UserID || folder.Name != arg.OldName {
+			continue
+		}
+		for _, other := range db.folders {
+			if other.ID != id && other.UserID == arg.UserID && other.Name == arg.NewName {
+				return database.Folder{}, &UniqueViolation{Constraint: "unique_user_folder"}
+			}
+		}
+		folder.Name = arg.NewName
+		folder.UpdatedAt = db.now()
+		db.folders[id] = folder
+		return folder, nil
+	}
+	return database.Folder{}, sql.ErrNoRows
+}
+
+func (db *DB) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, ok := db.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if other, ok := db.userByName(arg.Name); ok && other.ID != user.ID {
+		return database.User{}, &UniqueViolation{Constraint: "users_name_key"}
+	}
+	user.Name = arg.Name
+	user.UpdatedAt = db.now()
+	db.users[user.ID] = user
+	return user, nil
+}
+
+// ResetNumSequences moves the num counters past the restored rows, like
+// the sequences on Postgres.
+func (db *DB) ResetNumSequences(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, feed := range db.feeds {
+		db.feedNum = max(db.feedNum, feed.Num)
+	}
+	for _, folder := range db.folders {
+		db.folderNum = max(db.folderNum, folder.Num)
+	}
+	for _, post := range db.posts {
+		db.postNum = max(db.postNum, post.Num)
+	}
+	return nil
+}
+
+func (db *DB) RestoreAPIToken(ctx context.Context, arg database.RestoreAPITokenParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.tokens[arg.ID]; ok {
+		return &UniqueViolation{Constraint: "api_tokens_pkey"}
+	}
+	if _, ok := db.users[arg.UserID]; !ok {
+		return fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	for _, token := range db.tokens {
+		if token.TokenHash == arg.TokenHash {
+			return &UniqueViolation{Constraint: "api_tokens_token_hash_key"}
+		}
+	}
+	db.tokens[arg.ID] = database.ApiToken(arg)
+	return nil
+}
+
+// RestoreFeed stores a feed with the num it is given.  The counter is
+// left alone until ResetNumSequences.
+func (db *DB) RestoreFeed(ctx context.Context, arg database.RestoreFeedParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.feeds[arg.ID]; ok {
+		return &UniqueViolation{Constraint: "feeds_pkey"}
+	}
+	if _, ok := db.users[arg.UserID.UUID]; arg.UserID.Valid && !ok {
+		return fmt.Errorf("user %v does not exist", arg.UserID.UUID)
+	}
+	feed := database.Feed(arg)
+	if err := db.checkFeedUnique(feed); err != nil {
+		return err
+	}
+	for _, other := range db.feeds {
+		if other.Num == feed.Num {
+			return &UniqueViolation{Constraint: "feeds_num_key"}
+		}
+	}
+	db.feeds[feed.ID] = feed
+	return nil
+}
+
+func (db *DB) RestoreFeedFollow(ctx context.Context, arg database.RestoreFeedFollowParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.follows[arg.ID]; ok {
+		return &UniqueViolation{Constraint: "feed_follows_pkey"}
+	}
+	if _, ok := db.users[arg.UserID]; !ok {
+		return fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	if _, ok := db.feeds[arg.FeedID]; !ok {
+		return fmt.Errorf("feed %v does not exist", arg.FeedID)
+	}
+	if _, ok := db.folders[arg.FolderID.UUID]; arg.FolderID.Valid && !ok {
+		return fmt.Errorf("folder %v does not exist", arg.FolderID.UUID)
+	}
+	for _, follow := range db.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return &UniqueViolation{Constraint: "unique_user_feed"}
+		}
+	}
+	db.follows[arg.ID] = database.FeedFollow(arg)
+	return nil
+}
+
+func (db *DB) RestoreFolder(ctx context.Context, arg database.RestoreFolderParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.folders[arg.ID]; ok {
+		return &UniqueViolation{Constraint: "folders_pkey"}
+	}
+	if _, ok := db.users[arg.UserID]; !ok {
+		return fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	for _, folder := range db.folders {
+		if folder.UserID == arg.UserID && folder.Name == arg.Name {
+			return &UniqueViolation{Constraint: "unique_user_folder"}
+		}
+		if folder.Num == arg.Num {
+			return &UniqueViolation{Constraint: "folders_num_key"}
+		}
+	}
+	db.folders[arg.ID] = database.Folder(arg)
+	return nil
+}
+
+func (db *DB) RestorePost(ctx context.Context, arg database.RestorePostParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.posts[arg.ID]; ok {
+		return &UniqueViolation{Constraint: "posts_pkey"}
+	}
+	if _, ok := db.feeds[arg.FeedID]; !ok {
+		return fmt.Errorf("feed %v does not exist", arg.FeedID)
+	}
+	for _, post := range db.posts {
+		if post.Url == arg.Url {
+			return &UniqueViolation{Constraint: "posts_url_key"}
+		}
+		if post.Num == arg.Num {
+			return &UniqueViolation{Constraint: "posts_num_key"}
+		}
+	}
+	db.posts[arg.ID] = database.Post(arg)
+	return nil
+}
+
+func (db *DB) RestorePrunedPost(ctx context.Context, arg database.RestorePrunedPostParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.pruned[arg.Url]; ok {
+		return &UniqueViolation{Constraint: "pruned_posts_pkey"}
+	}
+	if _, ok := db.feeds[arg.FeedID]; !ok {
+		return fmt.Errorf("feed %v does not exist", arg.FeedID)
+	}
+	db.pruned[arg.Url] = database.PrunedPost(arg)
+	return nil
+}
+
+func (db *DB) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.folders[arg.FolderID.UUID]; arg.FolderID.Valid && !ok {
+		return 0, fmt.Errorf("folder %v does not exist", arg.FolderID.UUID)
+	}
+	var count int64
+	for id, follow := range db.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			follow.FolderID = arg.FolderID
+			follow.UpdatedAt = db.now()
+			db.follows[id] = follow
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) SetFeedFollowTitle(ctx context.Context, arg database.SetFeedFollowTitleParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var count int64
+	for id, follow := range db.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			follow.Title = arg.Title
+			follow.UpdatedAt = db.now()
+			db.follows[id] = follow
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feed, ok := db.feeds[arg.ID]
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.KeepDays = arg.KeepDays
+	feed.KeepPosts = arg.KeepPosts
+	feed.UpdatedAt = db.now()
+	db.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (db *DB) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, ok := db.users[arg.ID]
+	if !ok {
+		return nil
+	}
+	user.PasswordHash = arg.PasswordHash
+	user.UpdatedAt = db.now()
+	db.users[user.ID] = user
+	return nil
+}
+
+func (db *DB) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, ok := db.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if err := db.checkRole(arg.Role); err != nil {
+		return database.User{}, err
+	}
+	user.Role = arg.Role
+	user.UpdatedAt = db.now()
+	db.users[user.ID] = user
+	return user, nil
+}
+
+func (db *DB) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.UserID]; !ok {
+		return fmt.Errorf("user %v does not exist", arg.UserID)
+	}
+	if _, ok := db.posts[arg.PostID]; !ok {
+		return fmt.Errorf("post %v does not exist", arg.PostID)
+	}
+	key := postKey{UserID: arg.UserID, PostID: arg.PostID}
+	if _, ok := db.stars[key]; !ok {
+		db.stars[key] = database.PostStar{UserID: arg.UserID, PostID: arg.PostID, StarredAt: arg.StarredAt}
+	}
+	return nil
+}
+
+func (db *DB) TouchAPIToken(ctx context.Context, tokenHash string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for id, token := range db.tokens {
+		if token.TokenHash == tokenHash {
+			token.LastUsedAt = sql.NullTime{Time: db.now(), Valid: true}
+			db.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (db *DB) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.NewUserID.UUID]; arg.NewUserID.Valid && !ok {
+		return 0, fmt.Errorf("user %v does not exist", arg.NewUserID.UUID)
+	}
+	var count int64
+	for id, feed := range db.feeds {
+		if arg.OldUserID.Valid && feed.UserID == arg.OldUserID {
+			feed.UserID = arg.NewUserID
+			feed.UpdatedAt = db.now()
+			db.feeds[id] = feed
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *DB) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.stars, postKey{UserID: arg.UserID, PostID: arg.PostID})
+	return nil
+}
+
+func (db *DB) UpdateFeedUrl(ctx context.Context, arg database.UpdateFeedUrlParams) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	feed, ok := db.feeds[arg.ID]
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.Url = arg.Url
+	if err := db.checkFeedUnique(feed); err != nil {
+		return database.Feed{}, err
+	}
+	feed.UpdatedAt = db.now()
+	feed.LastFetchedAt = sql.NullTime{}
+	db.feeds[feed.ID] = feed
+	return feed, nil
+}
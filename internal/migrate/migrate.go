@@ -0,0 +1,271 @@
+// Package migrate applies goose style migrations.  It keeps track of them
+// in goose's goose_db_version table, so databases set up with goose and
+// databases set up with gator can be upgraded either way.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const versionTable = "goose_db_version"
+
+// Dialect holds the SQL that differs between databases.
+type Dialect struct {
+	tableExists string
+	createTable string
+}
+
+var (
+	Postgres = Dialect{
+		tableExists: "SELECT to_regclass($1) IS NOT NULL",
+		createTable: `CREATE TABLE ` + versionTable + ` (
+	id serial NOT NULL,
+	version_id bigint NOT NULL,
+	is_applied boolean NOT NULL,
+	tstamp timestamp NULL default now(),
+	PRIMARY KEY(id)
+)`,
+	}
+	SQLite = Dialect{
+		tableExists: "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)",
+		createTable: `CREATE TABLE ` + versionTable + ` (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	version_id INTEGER NOT NULL,
+	is_applied INTEGER NOT NULL,
+	tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+	}
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads the *.sql migrations in fsys, ordered by version.  Each file
+// name starts with its version number, and the file is split into Up and
+// Down sections by "-- +goose Up" and "-- +goose Down" lines.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	seen := make(map[int64]string)
+	for _, name := range names {
+		prefix, _, found := strings.Cut(path.Base(name), "_")
+		if !found {
+			return nil, fmt.Errorf("migration %v: file name must start with a version number and _", name)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %v: invalid version %v", name, prefix)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %v and %v have the same version", other, name)
+		}
+		seen[version] = name
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		migration, err := parse(name, version, string(data))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func parse(name string, version int64, data string) (Migration, error) {
+	var section *strings.Builder
+	var up, down strings.Builder
+	for _, line := range strings.SplitAfter(data, "\n") {
+		annotation, found := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if !found {
+			if section != nil {
+				section.WriteString(line)
+			}
+			continue
+		}
+		switch strings.TrimSpace(annotation) {
+		case "Up":
+			section = &up
+		case "Down":
+			section = &down
+		case "StatementBegin", "StatementEnd":
+			// Each section runs as a single Exec, so there is nothing
+			// to split.
+		default:
+			return Migration{}, fmt.Errorf("migration %v: unsupported annotation %v", name, strings.TrimSpace(line))
+		}
+	}
+	if up.Len() == 0 {
+		return Migration{}, fmt.Errorf("migration %v: missing -- +goose Up", name)
+	}
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up:      strings.TrimSpace(up.String()),
+		Down:    strings.TrimSpace(down.String()),
+	}, nil
+}
+
+// Latest returns the version of the newest migration, or 0 if there are
+// none.
+func Latest(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Applied returns the set of migration versions applied to db.  A
+// database that has never been migrated has none.
+func Applied(ctx context.Context, db *sql.DB, dialect Dialect) (map[int64]bool, error) {
+	exists, err := tableExists(ctx, db, dialect)
+	if err != nil || !exists {
+		return map[int64]bool{}, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied FROM "+versionTable+" ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// Like goose, the newest row for a version decides whether it is
+	// applied.
+	seen := make(map[int64]bool)
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if isApplied && version > 0 {
+			applied[version] = true
+		}
+	}
+	return applied, rows.Err()
+}
+
+// Version returns the newest applied migration version, or 0.
+func Version(ctx context.Context, db *sql.DB, dialect Dialect) (int64, error) {
+	applied, err := Applied(ctx, db, dialect)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+// Up applies every migration that has not been applied yet, in order.
+// Each migration runs in its own transaction.  done is called after each
+// one succeeds.
+func Up(ctx context.Context, db *sql.DB, dialect Dialect, migrations []Migration, done func(Migration)) error {
+	err := ensureTable(ctx, db, dialect)
+	if err != nil {
+		return err
+	}
+	applied, err := Applied(ctx, db, dialect)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		err = run(ctx, db, migration.Up, "INSERT INTO "+versionTable+" (version_id, is_applied) VALUES ($1, true)", migration.Version)
+		if err != nil {
+			return fmt.Errorf("migration %v: %w", migration.Name, err)
+		}
+		done(migration)
+	}
+	return nil
+}
+
+// Down rolls back the newest applied migration and returns it.  It
+// returns false if nothing is applied.
+func Down(ctx context.Context, db *sql.DB, dialect Dialect, migrations []Migration) (Migration, bool, error) {
+	version, err := Version(ctx, db, dialect)
+	if err != nil || version == 0 {
+		return Migration{}, false, err
+	}
+	for _, migration := range migrations {
+		if migration.Version != version {
+			continue
+		}
+		err = run(ctx, db, migration.Down, "DELETE FROM "+versionTable+" WHERE version_id = $1", migration.Version)
+		if err != nil {
+			return Migration{}, false, fmt.Errorf("migration %v: %w", migration.Name, err)
+		}
+		return migration, true, nil
+	}
+	return Migration{}, false, fmt.Errorf("applied version %v has no migration file", version)
+}
+
+// run executes a migration's SQL and records it in the version table, in
+// one transaction.
+func run(ctx context.Context, db *sql.DB, statements, record string, version int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if statements != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func tableExists(ctx context.Context, db *sql.DB, dialect Dialect) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, dialect.tableExists, versionTable).Scan(&exists)
+	return exists, err
+}
+
+// ensureTable creates the version table the way goose does, including
+// its version 0 row.
+func ensureTable(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	exists, err := tableExists(ctx, db, dialect)
+	if err != nil || exists {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, dialect.createTable)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO "+versionTable+" (version_id, is_applied) VALUES (0, true)")
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,283 @@
+// Package sqlitedb runs gator's generated queries against SQLite.  The
+// code in internal/database is generated from the Postgres queries, so
+// every query it sends is swapped for the SQLite version of the same name
+// from sql/sqlite/queries before it reaches the driver.
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/lucoand/gator/sql/sqlite"
+	sqlite3 "modernc.org/sqlite"
+	sqlite3lib "modernc.org/sqlite/lib"
+)
+
+// DB is a database.DBTX for a SQLite file.
+type DB struct {
+	*sql.DB
+	queries map[string]string
+}
+
+// Tx is a database.DBTX for a transaction on a DB.
+type Tx struct {
+	*sql.Tx
+	queries map[string]string
+}
+
+// Open opens the SQLite database at path, creating the file if needed.
+func Open(path string) (*DB, error) {
+	queries, err := loadQueries(sqlite.Queries)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_format", "sqlite")
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time.  A single connection queues
+	// writers here instead of failing them with SQLITE_BUSY, and keeps a
+	// :memory: database from being a different one on every connection.
+	db.SetMaxOpenConns(1)
+	return &DB{DB: db, queries: queries}, nil
+}
+
+// loadQueries reads the "-- name: X :kind" blocks that sqlc uses into a
+// map from query name to SQL.
+func loadQueries(fsys fs.FS) (map[string]string, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	queries := make(map[string]string)
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		blocks := strings.Split(string(data), "-- name: ")
+		for _, block := range blocks[1:] {
+			query := "-- name: " + strings.TrimSpace(block)
+			queryName, ok := queryName(query)
+			if !ok {
+				return nil, fmt.Errorf("%v: invalid query header %q", name, strings.SplitN(query, "\n", 2)[0])
+			}
+			if _, ok := queries[queryName]; ok {
+				return nil, fmt.Errorf("%v: query %v is defined twice", name, queryName)
+			}
+			queries[queryName] = query
+		}
+	}
+	return queries, nil
+}
+
+// queryName returns the name in a query's sqlc header.
+func queryName(query string) (string, bool) {
+	header, _, _ := strings.Cut(query, "\n")
+	fields := strings.Fields(header)
+	if len(fields) != 4 || fields[0] != "--" || fields[1] != "name:" {
+		return "", false
+	}
+	return fields[2], true
+}
+
+// timeFormat is how the driver stores times with _time_format=sqlite.
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// rewrite swaps a generated query for its SQLite version.  Queries without
+// a sqlc header are passed through unchanged.  Times are stored in UTC so
+// that they sort and compare correctly as text, and arrays, which SQLite
+// does not have, are passed as JSON for json_each to read.
+func rewrite(queries map[string]string, query string, args []any) (string, []any, error) {
+	if name, ok := queryName(query); ok {
+		sqliteQuery, ok := queries[name]
+		if !ok {
+			return "", nil, fmt.Errorf("query %v has no SQLite version", name)
+		}
+		query = sqliteQuery
+	}
+	var err error
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			args[i] = t.UTC()
+		case sql.NullTime:
+			if t.Valid {
+				args[i] = t.Time.UTC()
+			}
+		case *pq.StringArray:
+			args[i], err = jsonArray(reflect.ValueOf([]string(*t)))
+		case pq.GenericArray:
+			args[i], err = jsonArray(reflect.ValueOf(t.A))
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return query, args, nil
+}
+
+// jsonArray encodes the slice held by a pq array.  Elements are converted
+// the way the driver would store them on their own.
+func jsonArray(slice reflect.Value) (string, error) {
+	elems := make([]any, slice.Len())
+	for i := range elems {
+		var err error
+		switch elem := slice.Index(i).Interface().(type) {
+		case time.Time:
+			elems[i] = elem.UTC().Format(timeFormat)
+		case driver.Valuer:
+			elems[i], err = elem.Value()
+		default:
+			elems[i] = elem
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	data, err := json.Marshal(elems)
+	return string(data), err
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query, args, err := rewrite(db.queries, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	query, _, err := rewrite(db.queries, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return db.DB.PrepareContext(ctx, query)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query, args, err := rewrite(db.queries, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext cannot return an error of its own, so a query with no
+// SQLite version is sent as is and fails in the driver instead.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if rewritten, rewrittenArgs, err := rewrite(db.queries, query, args); err == nil {
+		query, args = rewritten, rewrittenArgs
+	}
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction whose queries are rewritten like the DB's.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, queries: db.queries}, nil
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query, args, err := rewrite(tx.queries, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Tx.ExecContext(ctx, query, args...)
+}
+
+func (tx *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	query, _, err := rewrite(tx.queries, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Tx.PrepareContext(ctx, query)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query, args, err := rewrite(tx.queries, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Tx.QueryContext(ctx, query, args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if rewritten, rewrittenArgs, err := rewrite(tx.queries, query, args); err == nil {
+		query, args = rewritten, rewrittenArgs
+	}
+	return tx.Tx.QueryRowContext(ctx, query, args...)
+}
+
+// Lock takes an exclusive lock on the SQLite file at path, creating it if
+// needed, and holds it until release is called or the process exits.  It
+// reports false if another process holds the lock.
+func Lock(path string) (release func() error, ok bool, err error) {
+	// Without a busy timeout, a lock that is taken fails straight away
+	// instead of waiting for it.
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(0)")
+	if err != nil {
+		return nil, false, err
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, false, err
+	}
+	_, err = conn.ExecContext(ctx, "BEGIN EXCLUSIVE")
+	if err != nil {
+		conn.Close()
+		db.Close()
+		var sqliteErr *sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3lib.SQLITE_BUSY {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	release = func() error {
+		_, err := conn.ExecContext(ctx, "ROLLBACK")
+		conn.Close()
+		db.Close()
+		return err
+	}
+	return release, true, nil
+}
+
+// UniqueViolation reports whether err is a unique or primary key
+// constraint violation, and if so which columns it was on, like
+// "posts.url".
+func UniqueViolation(err error) (string, bool) {
+	var sqliteErr *sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return "", false
+	}
+	switch sqliteErr.Code() {
+	case sqlite3lib.SQLITE_CONSTRAINT_UNIQUE, sqlite3lib.SQLITE_CONSTRAINT_PRIMARYKEY:
+	default:
+		return "", false
+	}
+	// The message ends "UNIQUE constraint failed: posts.url (2067)".
+	message := sqliteErr.Error()
+	columns := message[strings.LastIndex(message, "failed: ")+len("failed: "):]
+	columns, _, _ = strings.Cut(columns, " (")
+	return columns, true
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
 	"encoding/xml"
@@ -11,8 +10,11 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/araddon/dateparse"
@@ -21,13 +23,33 @@ import (
 	"github.com/lib/pq"
 	"github.com/lucoand/gator/internal/config"
 	"github.com/lucoand/gator/internal/database"
+	"github.com/lucoand/gator/internal/memdb"
+	"github.com/lucoand/gator/internal/sqlitedb"
 )
 
 type state struct {
-	db  *database.Queries
+	db  database.Querier
 	cfg *config.Config
+	// ctx is cancelled on SIGINT or SIGTERM.  Commands run their queries
+	// and requests under it, so they stop instead of running on.
+	ctx context.Context
+	// client fetches feeds, with the configured http_timeout.
+	client *http.Client
+	// backend is the database behind db, for migrations and transactions.
+	backend *backend
+	// inTx is set while db is a transaction's queries; see withTx.
+	inTx bool
+	// asUser and asToken come from --user and --token, or GATOR_USER and
+	// GATOR_TOKEN.  They pick who a single command runs as, without
+	// touching the config file.
+	asUser  string
+	asToken string
 }
 
+// errInvalidArgument marks errors caused by bad input rather than by the
+// database, so the API can answer 400 instead of 500.
+var errInvalidArgument = errors.New("invalid argument")
+
 type command struct {
 	name string
 	args []string
@@ -54,14 +76,14 @@ type RSSItem struct {
 	PubDate     string `xml:"pubDate"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+func fetchFeed(ctx context.Context, client *http.Client, feedURL string) (*RSSFeed, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
 		fmt.Println("ERROR: Unable to generate http request.")
 		return &RSSFeed{}, err
 	}
 	req.Header.Set("User-Agent", "gator")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Println("ERROR: Bad http response.")
 		return &RSSFeed{}, err
@@ -105,67 +127,196 @@ func (c *commands) register(name string, f func(*state, command) error) {
 	c.funcs[name] = f
 }
 
+// parseFlags splits args into positional arguments and --name=value
+// flags.  A bare --name is treated as --name=true.
+func parseFlags(args []string) ([]string, map[string]string) {
+	var positional []string
+	flags := make(map[string]string)
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !found {
+			value = "true"
+		}
+		flags[name] = value
+	}
+	return positional, flags
+}
+
+// globalFlags takes --user=<name> and --token=<token> out of argv, from
+// wherever they appear, and records them in s.  They win over the
+// environment variables.
+func globalFlags(s *state, argv []string) []string {
+	var rest []string
+	for _, arg := range argv {
+		if name, found := strings.CutPrefix(arg, "--user="); found {
+			s.asUser = name
+			continue
+		}
+		if token, found := strings.CutPrefix(arg, "--token="); found {
+			s.asToken = token
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest
+}
+
 func handlerLogin(s *state, cmd command) error {
 	if len(cmd.args) < 1 {
 		return errors.New("ERROR: expected one argument after \"login\"\nUsage: gator login <username>")
 	}
-	_, err := s.db.GetUser(context.Background(), cmd.args[0])
+	ctx := s.ctx
+	user, err := s.db.GetUser(ctx, cmd.args[0])
 	if err != nil {
 		fmt.Printf("ERROR: User %v not registered in database!  Try \"gator register <username>\" first\n", cmd.args[0])
 		return err
 	}
-	err = config.SetUser(cmd.args[0], *s.cfg)
+	// Users registered before passwords existed cannot log in until an
+	// admin sets one, or anyone could choose it for them.
+	if !user.PasswordHash.Valid {
+		return fmt.Errorf("ERROR: User %v has no password yet.  Ask an admin to set one with \"gator setpassword %v\"", user.Name, user.Name)
+	}
+	password, err := readPassword(ctx, "Password: ")
+	if err != nil {
+		return err
+	}
+	if checkPassword(user, password) != nil {
+		return errors.New("ERROR: Wrong password.")
+	}
+	err = startSession(ctx, s, user)
+	if err != nil {
+		fmt.Println("ERROR: Could not start session.")
+	}
 	return err
 }
 
-func handlerRegister(s *state, cmd command) error {
-	if len(cmd.args) < 1 {
-		return errors.New("ERROR: expected one argument after \"register\"\nUsage: gator register <username>")
+// createUser registers a new user.  It is shared by "gator register" and
+// the API.
+func createUser(ctx context.Context, s *state, name, password string) (database.User, error) {
+	if name == "" {
+		return database.User{}, fmt.Errorf("%w: user name cannot be empty", errInvalidArgument)
+	}
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return database.User{}, err
 	}
 	var args database.CreateUserParams
 	args.ID = uuid.New()
-	args.Name = cmd.args[0]
+	args.Name = name
+	args.PasswordHash = passwordHash
 	currentTime := time.Now()
 	args.CreatedAt = currentTime
 	args.UpdatedAt = currentTime
-	user, err := s.db.CreateUser(context.Background(), args)
+	var user database.User
+	err = withTx(ctx, s, func(tx *state) error {
+		// The first user of a new database administers it.  The lock
+		// keeps two users registering at once from both being first.
+		err := tx.db.LockUsers(ctx)
+		if err != nil {
+			return err
+		}
+		count, err := tx.db.CountUsers(ctx)
+		if err != nil {
+			return err
+		}
+		args.Role = roleUser
+		if count == 0 {
+			args.Role = roleAdmin
+		}
+		user, err = tx.db.CreateUser(ctx, args)
+		return err
+	})
+	return user, err
+}
+
+func handlerRegister(s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return errors.New("ERROR: expected one argument after \"register\"\nUsage: gator register <username>")
+	}
+	ctx := s.ctx
+	password, err := readNewPassword(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := createUser(ctx, s, cmd.args[0], password)
 	if err != nil {
 		fmt.Println("ERROR: Name already exists.")
 		return err
 	}
 	fmt.Printf("User %v was created.\n", user.Name)
 	// fmt.Printf("%v %v %v %v\n", user.ID, user.CreatedAt, user.UpdatedAt, user.Name)
-	err = config.SetUser(user.Name, *s.cfg)
+	err = startSession(ctx, s, user)
 	return err
 }
 
-func handlerReset(s *state, _ command) error {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("This will DELETE ALL data from the database, including user and feed data.  Are you sure? (yes/[no]): ")
-	input, _ := reader.ReadString('\n')
+// readInput runs read, which blocks on stdin, and returns what it read,
+// or ctx's error if ctx is cancelled first.  A read from stdin cannot be
+// interrupted, so it is left running, and gator exits soon after.
+func readInput(ctx context.Context, read func() (string, error)) (string, error) {
+	type result struct {
+		input string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		input, err := read()
+		done <- result{input, err}
+	}()
+	select {
+	case r := <-done:
+		return r.input, r.err
+	case <-ctx.Done():
+		fmt.Println()
+		return "", ctx.Err()
+	}
+}
+
+// confirm asks a yes or no question.  It reads from the shared stdin, so
+// a script can pipe in a password and then the answer.
+func confirm(ctx context.Context, prompt string) bool {
+	fmt.Printf("%v (yes/[no]): ", prompt)
+	input, _ := readInput(ctx, func() (string, error) {
+		return stdin.ReadString('\n')
+	})
 	input = strings.TrimSpace(strings.ToLower(input))
-	if input == "yes" {
-		err := s.db.DeleteAllUsers(context.Background())
-		if err != nil {
-			fmt.Println("ERROR: Could not reset users table.")
-			return err
-		}
-		fmt.Println("Users table reset successfully.")
-	} else {
-		fmt.Println("Aborted.")
+	return input == "yes"
+}
+
+// withTx runs fn with a copy of s whose queries all belong to one
+// transaction.  If fn returns an error nothing it did is kept.  Called
+// inside another withTx, fn joins the outer transaction.
+func withTx(ctx context.Context, s *state, fn func(tx *state) error) error {
+	if s.inTx {
+		return fn(s)
 	}
-	return nil
+	return s.backend.withTx(ctx, func(q database.Querier) error {
+		tx := *s
+		tx.db = q
+		tx.inTx = true
+		return fn(&tx)
+	})
 }
 
 func handlerUsers(s *state, _ command) error {
-	users, err := s.db.GetUsers(context.Background())
+	users, err := s.db.ListUsers(s.ctx)
 	if err != nil {
 		fmt.Println("ERROR: Could not retrieve list of users.")
 		return err
 	}
+	current := s.cfg.Username
+	if s.asUser != "" {
+		current = s.asUser
+	}
 	for _, user := range users {
-		output := "* " + user
-		if user == s.cfg.Username {
+		output := "* " + user.Name
+		if user.Role == roleAdmin {
+			output += " (admin)"
+		}
+		if user.Name == current {
 			output += " (current)"
 		}
 		fmt.Println(output)
@@ -173,46 +324,255 @@ func handlerUsers(s *state, _ command) error {
 	return nil
 }
 
-func handleAgg(s *state, cmd command) error {
-	if len(cmd.args) < 1 {
-		return fmt.Errorf("ERROR: agg requires an argument.\nUsage: \"gator agg <interval>\".  Interval can be of a form like 1m, 1h, etc.  Must be at least 1m.")
+// handleSetrole makes a user an admin or a regular user.  The last admin
+// cannot be demoted, so there is always someone who can run admin
+// commands.
+func handleSetrole(s *state, cmd command, _ database.User) error {
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("ERROR: Not enough arguments.\nUsage: gator setrole <username> <admin|user>")
+	}
+	role := cmd.args[1]
+	if role != roleAdmin && role != roleUser {
+		return fmt.Errorf("ERROR: Unknown role %v.  Expected %v or %v.", role, roleAdmin, roleUser)
+	}
+	ctx := s.ctx
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		fmt.Printf("ERROR: User %v not registered in database!\n", cmd.args[0])
+		return err
+	}
+	if target.Role == roleAdmin && role != roleAdmin {
+		err = checkNotLastAdmin(ctx, s, target)
+		if err != nil {
+			return err
+		}
 	}
-	const minInterval = 1 * time.Minute
-	timeBetweenRequests, err := time.ParseDuration(cmd.args[0])
+	var arg database.SetUserRoleParams
+	arg.ID = target.ID
+	arg.Role = role
+	_, err = s.db.SetUserRole(ctx, arg)
 	if err != nil {
-		fmt.Printf("ERROR: Unable to parse duration \"%v\"\n", cmd.args[0])
+		fmt.Printf("ERROR: Could not set role for user %v\n", target.Name)
 		return err
 	}
-	if timeBetweenRequests < minInterval {
-		fmt.Printf("Interval too short!  Must be at least %v\n", minInterval)
+	fmt.Printf("User %v is now %v.\n", target.Name, role)
+	return nil
+}
+
+// checkNotLastAdmin returns an error if user is the only admin left.
+func checkNotLastAdmin(ctx context.Context, s *state, user database.User) error {
+	if user.Role != roleAdmin {
 		return nil
 	}
-	fmt.Printf("Collecting feeds every %v\n", timeBetweenRequests)
-	ticker := time.NewTicker(timeBetweenRequests)
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+	admins, err := s.db.CountAdmins(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not count admins.")
+		return err
+	}
+	if admins <= 1 {
+		return fmt.Errorf("ERROR: %v is the only admin.  Make another user admin first with \"gator setrole <username> admin\"", user.Name)
 	}
+	return nil
 }
 
-func handleAddfeed(s *state, cmd command, user database.User) error {
+// handleDeleteuser deletes a user along with their follows, folders,
+// read state and tokens.  The feeds they added stay for everyone else,
+// either without an owner or, with --transfer, owned by another user.
+func handleDeleteuser(s *state, cmd command, user database.User) error {
+	args, flags := parseFlags(cmd.args)
+	if len(args) < 1 {
+		return fmt.Errorf("ERROR: Not enough arguments.\nUsage: gator deleteuser <username> [--transfer=<username>]")
+	}
+	ctx := s.ctx
+	target, err := s.db.GetUser(ctx, args[0])
+	if err != nil {
+		fmt.Printf("ERROR: User %v not registered in database!\n", args[0])
+		return err
+	}
+	if target.ID != user.ID && user.Role != roleAdmin {
+		return fmt.Errorf("ERROR: User %v cannot delete user %v.  Only admins can delete other users.", user.Name, target.Name)
+	}
+	err = checkNotLastAdmin(ctx, s, target)
+	if err != nil {
+		return err
+	}
+	var heir database.User
+	heirName, transfer := flags["transfer"]
+	if transfer {
+		heir, err = s.db.GetUser(ctx, heirName)
+		if err != nil {
+			fmt.Printf("ERROR: User %v not registered in database!\n", heirName)
+			return err
+		}
+		if heir.ID == target.ID {
+			return fmt.Errorf("ERROR: Cannot transfer feeds to the user being deleted.")
+		}
+	}
+
+	stats, err := s.db.GetUserStats(ctx, target.ID)
+	if err != nil {
+		fmt.Printf("ERROR: Could not get stats for user %v\n", target.Name)
+		return err
+	}
+	fmt.Printf("User %v follows %v feeds, and added %v feeds.\n", target.Name, stats.FollowCount, stats.FeedsAdded)
+	if stats.FeedsAdded > 0 {
+		if transfer {
+			fmt.Printf("The feeds %v added will be transferred to %v.\n", target.Name, heir.Name)
+		} else {
+			fmt.Printf("The feeds %v added will be kept without an owner.  Use --transfer=<username> to give them to another user.\n", target.Name)
+		}
+	}
+	if !confirm(ctx, fmt.Sprintf("Delete user %v?", target.Name)) {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	// The feeds must not be left ownerless if the delete fails after the
+	// transfer, or transferred if it fails before.
+	err = withTx(ctx, s, func(tx *state) error {
+		if transfer {
+			var arg database.TransferFeedsParams
+			arg.NewUserID = uuid.NullUUID{UUID: heir.ID, Valid: true}
+			arg.OldUserID = uuid.NullUUID{UUID: target.ID, Valid: true}
+			_, err := tx.db.TransferFeeds(ctx, arg)
+			if err != nil {
+				fmt.Println("ERROR: Could not transfer feeds.")
+				return err
+			}
+		}
+		_, err := tx.db.DeleteUser(ctx, target.ID)
+		if err != nil {
+			fmt.Printf("ERROR: Could not delete user %v\n", target.Name)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("User %v deleted.\n", target.Name)
+	if target.Name == s.cfg.Username {
+		return config.ClearUser(*s.cfg)
+	}
+	return nil
+}
+
+func handleRenameuser(s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 2 {
-		return fmt.Errorf("ERROR: addfeed requires two arguments.\nUsage: gator addfeed <feedName> <url>")
+		return fmt.Errorf("ERROR: Not enough arguments.\nUsage: gator renameuser <username> <new_name>")
+	}
+	ctx := s.ctx
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		fmt.Printf("ERROR: User %v not registered in database!\n", cmd.args[0])
+		return err
+	}
+	if target.ID != user.ID && user.Role != roleAdmin {
+		return fmt.Errorf("ERROR: User %v cannot rename user %v.  Only admins can rename other users.", user.Name, target.Name)
+	}
+	if cmd.args[1] == "" {
+		return fmt.Errorf("ERROR: User name cannot be empty.")
+	}
+	var arg database.RenameUserParams
+	arg.ID = target.ID
+	arg.Name = cmd.args[1]
+	renamed, err := s.db.RenameUser(ctx, arg)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("ERROR: User %v already exists.", cmd.args[1])
+		}
+		fmt.Printf("ERROR: Could not rename user %v\n", target.Name)
+		return err
+	}
+	fmt.Printf("User %v renamed to %v.\n", target.Name, renamed.Name)
+
+	// Fever api_keys are derived from the user name, so the old one can
+	// never match again.
+	var tokenArg database.DeleteAPITokensByNameParams
+	tokenArg.UserID = target.ID
+	tokenArg.Name = feverTokenName
+	removed, err := s.db.DeleteAPITokensByName(ctx, tokenArg)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		fmt.Println("The Fever password was cleared; set it again with \"gator feverkey\"")
+	}
+	if target.Name == s.cfg.Username {
+		return config.SetUser(renamed.Name, s.cfg.Token, *s.cfg)
+	}
+	return nil
+}
+
+// handleUserinfo prints stats for a user, the current user by default.
+func handleUserinfo(s *state, cmd command, user database.User) error {
+	ctx := s.ctx
+	if len(cmd.args) > 0 && cmd.args[0] != user.Name {
+		var err error
+		user, err = s.db.GetUser(ctx, cmd.args[0])
+		if err != nil {
+			fmt.Printf("ERROR: User %v not registered in database!\n", cmd.args[0])
+			return err
+		}
+	}
+	stats, err := s.db.GetUserStats(ctx, user.ID)
+	if err != nil {
+		fmt.Printf("ERROR: Could not get stats for user %v\n", user.Name)
+		return err
+	}
+	password := "not set"
+	if user.PasswordHash.Valid {
+		password = "set"
+	}
+	fmt.Printf("User %v:\n", user.Name)
+	fmt.Println("  ID:", user.ID)
+	fmt.Println("  REGISTERED:", user.CreatedAt.Format(time.DateTime))
+	fmt.Println("  ROLE:", user.Role)
+	fmt.Println("  PASSWORD:", password)
+	fmt.Println("  FEEDS ADDED:", stats.FeedsAdded)
+	fmt.Printf("  FOLLOWING: %v feeds in %v folders\n", stats.FollowCount, stats.FolderCount)
+	fmt.Printf("  POSTS: %v, %v unread, %v starred\n", stats.PostCount, stats.UnreadCount, stats.StarredCount)
+	fmt.Println("  TOKENS:", stats.TokenCount)
+	return nil
+}
+
+// createFeed adds a feed to the database and follows it for the user who
+// added it.  Both happen in one transaction, so a failed follow leaves no
+// feed behind.
+func createFeed(ctx context.Context, s *state, user database.User, name, url string) (database.Feed, error) {
+	if name == "" || url == "" {
+		return database.Feed{}, fmt.Errorf("%w: feed name and url cannot be empty", errInvalidArgument)
 	}
 	currentTime := time.Now()
 	arg := database.CreateFeedParams{}
 	arg.ID = uuid.New()
 	arg.CreatedAt = currentTime
 	arg.UpdatedAt = currentTime
-	arg.Name = cmd.args[0]
-	arg.Url = cmd.args[1]
-	arg.UserID = user.ID
-	feed, err := s.db.CreateFeed(context.Background(), arg)
-	if err != nil {
-		fmt.Println("ERROR: Could not create feed.")
+	arg.Name = name
+	arg.Url = url
+	arg.UserID = uuid.NullUUID{UUID: user.ID, Valid: true}
+	var feed database.Feed
+	err := withTx(ctx, s, func(tx *state) error {
+		var err error
+		feed, err = tx.db.CreateFeed(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = addFollow(ctx, tx, user.ID, feed.ID)
 		return err
+	})
+	if err != nil {
+		return database.Feed{}, err
 	}
-	_, err = addFollow(s, user.ID, feed.ID)
+	return feed, nil
+}
+
+func handleAddfeed(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("ERROR: addfeed requires two arguments.\nUsage: gator addfeed <feedName> <url>")
+	}
+	feed, err := createFeed(s.ctx, s, user, cmd.args[0], cmd.args[1])
 	if err != nil {
+		fmt.Println("ERROR: Could not create feed.")
 		return err
 	}
 	// fmt.Printf("%v %v %v %v %v %v\n", feed.ID, feed.CreatedAt, feed.UpdatedAt, feed.Name, feed.Url, feed.UserID)
@@ -221,19 +581,171 @@ func handleAddfeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handleFeeds(s *state, _ command) error {
-	feeds, err := s.db.GetFeeds(context.Background())
+// feedCatalog lists every feed with its follower and post counts.  Feeds
+// followed by userID are marked as followed; pass uuid.Nil when there is
+// no user.  Only feeds whose name, url or creator contains search are
+// returned.
+func feedCatalog(ctx context.Context, s *state, userID uuid.UUID, search, sortBy string) ([]database.GetFeedCatalogRow, error) {
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	var less func(a, b database.GetFeedCatalogRow) bool
+	switch sortBy {
+	case "name":
+		less = func(a, b database.GetFeedCatalogRow) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "followers":
+		less = func(a, b database.GetFeedCatalogRow) bool { return a.FollowerCount > b.FollowerCount }
+	case "posts":
+		less = func(a, b database.GetFeedCatalogRow) bool { return a.PostCount > b.PostCount }
+	case "updated":
+		less = func(a, b database.GetFeedCatalogRow) bool { return a.LastPostAt.Time.After(b.LastPostAt.Time) }
+	default:
+		return nil, fmt.Errorf("%w: unknown sort %v, expected name, followers, posts or updated", errInvalidArgument, sortBy)
+	}
+	feeds, err := s.db.GetFeedCatalog(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if search != "" {
+		search = strings.ToLower(search)
+		var matches []database.GetFeedCatalogRow
+		for _, feed := range feeds {
+			if strings.Contains(strings.ToLower(feed.Name), search) ||
+				strings.Contains(strings.ToLower(feed.Url), search) ||
+				strings.Contains(strings.ToLower(feed.UserName.String), search) {
+				matches = append(matches, feed)
+			}
+		}
+		feeds = matches
+	}
+	sort.SliceStable(feeds, func(i, j int) bool { return less(feeds[i], feeds[j]) })
+	return feeds, nil
+}
+
+func handleFeeds(s *state, cmd command) error {
+	args, flags := parseFlags(cmd.args)
+	// Not being logged in is fine here; nothing will be marked as followed.
+	var userID uuid.UUID
+	user, err := currentUser(s.ctx, s)
+	if err == nil {
+		userID = user.ID
+	}
+	feeds, err := feedCatalog(s.ctx, s, userID, strings.Join(args, " "), flags["sort"])
 	if err != nil {
 		fmt.Println("ERROR: Could not retrieve feeds from database.")
 		return err
 	}
+	if len(feeds) < 1 {
+		fmt.Println("No feeds found.")
+		return nil
+	}
+
 	for _, feed := range feeds {
-		fmt.Printf("%v %v %v\n", feed.Name, feed.Url, feed.UserName)
+		output := "* " + shortID(feed.ID) + " " + feed.Name
+		if feed.Followed {
+			output += " (following)"
+		}
+		fmt.Println(output)
+		fmt.Println("  URL:", feed.Url)
+		if feed.UserName.Valid {
+			fmt.Println("  ADDED BY:", feed.UserName.String)
+		} else {
+			fmt.Println("  ADDED BY: (user deleted)")
+		}
+		fmt.Printf("  FOLLOWERS: %v  POSTS: %v\n", feed.FollowerCount, feed.PostCount)
+		if feed.LastPostAt.Valid {
+			fmt.Println("  LAST POST:", feed.LastPostAt.Time)
+		} else {
+			fmt.Println("  LAST POST: never")
+		}
+		if feed.LastFetchedAt.Valid {
+			fmt.Println("  LAST FETCHED:", feed.LastFetchedAt.Time)
+		} else {
+			fmt.Println("  LAST FETCHED: never")
+		}
+	}
+	return nil
+}
+
+func getOwnedFeed(s *state, user database.User, ref string) (database.Feed, error) {
+	feed, err := resolveFeed(s, ref)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if user.Role != roleAdmin && (!feed.UserID.Valid || feed.UserID.UUID != user.ID) {
+		return database.Feed{}, fmt.Errorf("ERROR: Feed %v was not added by user %v.  Only the user who added a feed, or an admin, can change it.", feed.Name, user.Name)
+	}
+	return feed, nil
+}
+
+func handleRenamefeed(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("ERROR: renamefeed requires two arguments.\nUsage: gator renamefeed <feed> <new_name>")
+	}
+	feed, err := getOwnedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	var arg database.RenameFeedParams
+	arg.ID = feed.ID
+	arg.Name = cmd.args[1]
+	renamed, err := s.db.RenameFeed(s.ctx, arg)
+	if err != nil {
+		fmt.Printf("ERROR: Could not rename feed.  Is the name %v already taken?\n", arg.Name)
+		return err
+	}
+	fmt.Printf("Feed %v renamed to %v.\n", feed.Name, renamed.Name)
+	return nil
+}
+
+func handleSetfeedurl(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("ERROR: setfeedurl requires two arguments.\nUsage: gator setfeedurl <feed> <new_url>")
+	}
+	feed, err := getOwnedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	var arg database.UpdateFeedUrlParams
+	arg.ID = feed.ID
+	arg.Url = cmd.args[1]
+	updated, err := s.db.UpdateFeedUrl(s.ctx, arg)
+	if err != nil {
+		fmt.Printf("ERROR: Could not change feed url.  Is %v already in the database?\n", arg.Url)
+		return err
+	}
+	fmt.Printf("Feed %v now fetches from %v\n", updated.Name, updated.Url)
+	return nil
+}
+
+func handleDeletefeed(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: deletefeed requires a feed url.\nUsage: gator deletefeed <feed>")
+	}
+	feed, err := getOwnedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	impact, err := s.db.GetFeedDeleteImpact(s.ctx, feed.ID)
+	if err != nil {
+		fmt.Println("ERROR: Could not count followers and posts for feed.")
+		return err
+	}
+	fmt.Printf("Deleting feed %v will also unfollow it for %v user(s) and delete %v post(s).\n", feed.Name, impact.FollowerCount, impact.PostCount)
+	if !confirm(s.ctx, "Are you sure?") {
+		fmt.Println("Aborted.")
+		return nil
 	}
+	err = s.db.DeleteFeed(s.ctx, feed.ID)
+	if err != nil {
+		fmt.Println("ERROR: Could not delete feed.")
+		return err
+	}
+	fmt.Printf("Feed %v deleted.\n", feed.Name)
 	return nil
 }
 
-func addFollow(s *state, userID uuid.UUID, feedID uuid.UUID) (database.CreateFeedFollowRow, error) {
+func addFollow(ctx context.Context, s *state, userID uuid.UUID, feedID uuid.UUID) (database.CreateFeedFollowRow, error) {
 	var arg database.CreateFeedFollowParams
 	arg.UserID = userID
 	arg.FeedID = feedID
@@ -241,33 +753,51 @@ func addFollow(s *state, userID uuid.UUID, feedID uuid.UUID) (database.CreateFee
 	arg.CreatedAt = currentTime
 	arg.UpdatedAt = currentTime
 	arg.ID = uuid.New()
-	feed_follow, err := s.db.CreateFeedFollow(context.Background(), arg)
-	if err != nil {
-		fmt.Println("Could not add feed follow to database.")
-		return database.CreateFeedFollowRow{}, err
-	}
-	return feed_follow, nil
+	return s.db.CreateFeedFollow(ctx, arg)
 }
 
 func handleFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 1 {
-		return fmt.Errorf("ERROR: follow command requires one argument.\n Usage: gator follow <url>")
+		return fmt.Errorf("ERROR: follow command requires one argument.\n Usage: gator follow <feed>")
 	}
-	feedID, err := s.db.GetFeedIDByUrl(context.Background(), cmd.args[0])
+	feed, err := resolveFeed(s, cmd.args[0])
 	if err != nil {
-		fmt.Println("Unable to retrieve feed from database.  This likely means the feed has not been added.\nTry adding with \"gator addfeed <feedname> <url>\"")
 		return err
 	}
-	feed_follow, err := addFollow(s, user.ID, feedID)
+	feed_follow, err := addFollow(s.ctx, s, user.ID, feed.ID)
 	if err != nil {
+		fmt.Println("Could not add feed follow to database.")
 		return err
 	}
 	fmt.Printf("%v %v\n", feed_follow.FeedName, feed_follow.UserName)
 	return nil
 }
 
-func handleFollowing(s *state, _ command, user database.User) error {
-	feeds, err := s.db.GetFeedFollowsForUser(context.Background(), user.Name)
+func handleFollowing(s *state, cmd command, user database.User) error {
+	if len(cmd.args) > 0 {
+		folder, err := getFolder(s, user, cmd.args[0])
+		if err != nil {
+			return err
+		}
+		var arg database.GetFeedFollowsForUserInFolderParams
+		arg.UserID = user.ID
+		arg.FolderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+		feeds, err := s.db.GetFeedFollowsForUserInFolder(s.ctx, arg)
+		if err != nil {
+			fmt.Printf("ERROR: Could not retrieve follows in folder %v\n", folder.Name)
+			return err
+		}
+		if len(feeds) < 1 {
+			fmt.Printf("No feeds in folder %v\n", folder.Name)
+			return nil
+		}
+		fmt.Printf("Feeds in folder %v:\n", folder.Name)
+		for _, feed := range feeds {
+			fmt.Println(feed.FeedName)
+		}
+		return nil
+	}
+	feeds, err := s.db.GetFeedFollowsForUser(s.ctx, user.Name)
 	if err != nil {
 		fmt.Printf("ERROR: Could not retrieve follows for user %v\n", user.Name)
 		return err
@@ -278,45 +808,320 @@ func handleFollowing(s *state, _ command, user database.User) error {
 	}
 	fmt.Printf("Feeds followed by user %v:\n", feeds[0].UserName)
 	for _, feed := range feeds {
-		fmt.Println(feed.FeedName)
+		if feed.FolderName.Valid {
+			fmt.Printf("%v [%v]\n", feed.FeedName, feed.FolderName.String)
+		} else {
+			fmt.Println(feed.FeedName)
+		}
 	}
 	return nil
 }
 
 func handleUnfollow(s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 1 {
-		return fmt.Errorf("ERROR: unfollow requires a feed url\nUsage: gator unfollow <url>")
+		return fmt.Errorf("ERROR: unfollow requires a feed\nUsage: gator unfollow <feed>")
 	}
-	var arg database.DeleteFeedFollowByUserNameAndFeedUrlParams
-	arg.UserName = user.Name
-	arg.FeedUrl = cmd.args[0]
-	count, err := s.db.DeleteFeedFollowByUserNameAndFeedUrl(context.Background(), arg)
+	feed, err := resolveFeed(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	var arg database.DeleteFeedFollowParams
+	arg.UserID = user.ID
+	arg.FeedID = feed.ID
+	count, err := s.db.DeleteFeedFollow(s.ctx, arg)
 	if err != nil {
 		fmt.Println("ERROR: Unable to delete follow.")
 		return err
 	}
 	if count < 1 {
-		fmt.Printf("User %v was already not following %v\n", arg.UserName, arg.FeedUrl)
+		fmt.Printf("User %v was already not following %v\n", user.Name, feed.Url)
+	} else {
+		fmt.Printf("%v unfollowed for user %v\n", feed.Url, user.Name)
+	}
+	return nil
+}
+
+func getFolder(s *state, user database.User, name string) (database.Folder, error) {
+	var arg database.GetFolderByNameParams
+	arg.UserID = user.ID
+	arg.Name = name
+	folder, err := s.db.GetFolderByName(s.ctx, arg)
+	if err != nil {
+		fmt.Printf("ERROR: Folder %v not found for user %v.  Try \"gator addfolder %v\" first\n", name, user.Name, name)
+		return database.Folder{}, err
+	}
+	return folder, nil
+}
+
+func handleFolders(s *state, _ command, user database.User) error {
+	folders, err := s.db.GetFoldersForUser(s.ctx, user.ID)
+	if err != nil {
+		fmt.Printf("ERROR: Could not retrieve folders for user %v\n", user.Name)
+		return err
+	}
+	if len(folders) < 1 {
+		fmt.Printf("No folders for user %v\n", user.Name)
+		return nil
+	}
+	fmt.Printf("Folders for user %v:\n", user.Name)
+	for _, folder := range folders {
+		fmt.Println("* " + folder.Name)
+	}
+	return nil
+}
+
+func handleAddfolder(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: addfolder requires a folder name.\nUsage: gator addfolder <folder>")
+	}
+	currentTime := time.Now()
+	var arg database.CreateFolderParams
+	arg.ID = uuid.New()
+	arg.CreatedAt = currentTime
+	arg.UpdatedAt = currentTime
+	arg.Name = cmd.args[0]
+	arg.UserID = user.ID
+	folder, err := s.db.CreateFolder(s.ctx, arg)
+	if err != nil {
+		fmt.Printf("ERROR: Could not create folder %v.  Does it already exist?\n", arg.Name)
+		return err
+	}
+	fmt.Printf("Folder %v created.\n", folder.Name)
+	return nil
+}
+
+func handleRenamefolder(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("ERROR: renamefolder requires two arguments.\nUsage: gator renamefolder <folder> <new_name>")
+	}
+	var arg database.RenameFolderParams
+	arg.UserID = user.ID
+	arg.OldName = cmd.args[0]
+	arg.NewName = cmd.args[1]
+	folder, err := s.db.RenameFolder(s.ctx, arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Printf("Folder %v not found for user %v\n", arg.OldName, user.Name)
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("ERROR: Could not rename folder %v.  Is %v already taken?\n", arg.OldName, arg.NewName)
+		return err
+	}
+	fmt.Printf("Folder %v renamed to %v.\n", arg.OldName, folder.Name)
+	return nil
+}
+
+func handleDelfolder(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: delfolder requires a folder name.\nUsage: gator delfolder <folder>")
+	}
+	var arg database.DeleteFolderParams
+	arg.UserID = user.ID
+	arg.Name = cmd.args[0]
+	count, err := s.db.DeleteFolder(s.ctx, arg)
+	if err != nil {
+		fmt.Printf("ERROR: Could not delete folder %v\n", arg.Name)
+		return err
+	}
+	if count < 1 {
+		fmt.Printf("Folder %v not found for user %v\n", arg.Name, user.Name)
 	} else {
-		fmt.Printf("%v unfollowed for user %v\n", arg.FeedUrl, arg.UserName)
+		fmt.Printf("Folder %v deleted.  Its feeds are still followed.\n", arg.Name)
 	}
 	return nil
 }
 
+func handleSetfolder(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: setfolder requires a feed.\nUsage: gator setfolder <feed> [folder]")
+	}
+	feed, err := resolveFeed(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	var arg database.SetFeedFollowFolderParams
+	arg.UserID = user.ID
+	arg.FeedID = feed.ID
+	folderName := ""
+	if len(cmd.args) > 1 {
+		folder, err := getFolder(s, user, cmd.args[1])
+		if err != nil {
+			return err
+		}
+		arg.FolderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+		folderName = folder.Name
+	}
+	count, err := s.db.SetFeedFollowFolder(s.ctx, arg)
+	if err != nil {
+		fmt.Println("ERROR: Unable to update folder for feed.")
+		return err
+	}
+	if count < 1 {
+		fmt.Printf("User %v is not following %v\n", user.Name, feed.Name)
+	} else if folderName == "" {
+		fmt.Printf("%v removed from its folder.\n", feed.Name)
+	} else {
+		fmt.Printf("%v moved to folder %v.\n", feed.Name, folderName)
+	}
+	return nil
+}
+
+func handleSettitle(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: settitle requires a feed.\nUsage: gator settitle <feed> [title]")
+	}
+	feed, err := resolveFeed(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	var arg database.SetFeedFollowTitleParams
+	arg.UserID = user.ID
+	arg.FeedID = feed.ID
+	if len(cmd.args) > 1 && cmd.args[1] != "" {
+		arg.Title = sql.NullString{String: cmd.args[1], Valid: true}
+	}
+	count, err := s.db.SetFeedFollowTitle(s.ctx, arg)
+	if err != nil {
+		fmt.Println("ERROR: Unable to update title for feed.")
+		return err
+	}
+	if count < 1 {
+		fmt.Printf("User %v is not following %v\n", user.Name, feed.Name)
+	} else if arg.Title.Valid {
+		fmt.Printf("%v will be shown as %v for user %v\n", feed.Name, arg.Title.String, user.Name)
+	} else {
+		fmt.Printf("Title for %v reset to the feed name.\n", feed.Name)
+	}
+	return nil
+}
+
+// postFilter narrows down the posts returned by postsForUser.
+type postFilter struct {
+	Folder      string
+	UnreadOnly  bool
+	StarredOnly bool
+}
+
+// postsForUser returns the posts from the feeds a user follows, newest
+// first.
+func postsForUser(ctx context.Context, s *state, user database.User, filter postFilter) ([]database.GetPostsForUserRow, error) {
+	var posts []database.GetPostsForUserRow
+	if filter.Folder != "" {
+		var folderArg database.GetFolderByNameParams
+		folderArg.UserID = user.ID
+		folderArg.Name = filter.Folder
+		f, err := s.db.GetFolderByName(ctx, folderArg)
+		if err != nil {
+			return nil, fmt.Errorf("folder %v not found for user %v: %w", filter.Folder, user.Name, err)
+		}
+		var arg database.GetPostsForUserInFolderParams
+		arg.UserID = user.ID
+		arg.FolderID = uuid.NullUUID{UUID: f.ID, Valid: true}
+		folderPosts, err := s.db.GetPostsForUserInFolder(ctx, arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range folderPosts {
+			posts = append(posts, database.GetPostsForUserRow(post))
+		}
+	} else {
+		var err error
+		posts, err = s.db.GetPostsForUser(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filter.UnreadOnly || filter.StarredOnly {
+		var matches []database.GetPostsForUserRow
+		for _, post := range posts {
+			if filter.UnreadOnly && post.ReadAt.Valid {
+				continue
+			}
+			if filter.StarredOnly && !post.StarredAt.Valid {
+				continue
+			}
+			matches = append(matches, post)
+		}
+		posts = matches
+	}
+	return posts, nil
+}
+
+// checkPostFollowed returns an error wrapping sql.ErrNoRows unless the
+// post is in a feed the user follows, so nobody can mark posts they
+// cannot see.
+func checkPostFollowed(ctx context.Context, s *state, user database.User, postID uuid.UUID) error {
+	var arg database.GetPostForUserParams
+	arg.ID = postID
+	arg.UserID = user.ID
+	_, err := s.db.GetPostForUser(ctx, arg)
+	if err != nil {
+		return fmt.Errorf("post %v not found in feeds followed by %v: %w", postID, user.Name, err)
+	}
+	return nil
+}
+
+// setPostRead marks a post as read or unread for a user.
+func setPostRead(ctx context.Context, s *state, user database.User, postID uuid.UUID, read bool) error {
+	err := checkPostFollowed(ctx, s, user, postID)
+	if err != nil {
+		return err
+	}
+	if !read {
+		var arg database.MarkPostUnreadParams
+		arg.UserID = user.ID
+		arg.PostID = postID
+		return s.db.MarkPostUnread(ctx, arg)
+	}
+	var arg database.MarkPostReadParams
+	arg.UserID = user.ID
+	arg.PostID = postID
+	arg.ReadAt = time.Now()
+	return s.db.MarkPostRead(ctx, arg)
+}
+
+// setPostStarred stars or unstars a post for a user.
+func setPostStarred(ctx context.Context, s *state, user database.User, postID uuid.UUID, starred bool) error {
+	err := checkPostFollowed(ctx, s, user, postID)
+	if err != nil {
+		return err
+	}
+	if !starred {
+		var arg database.UnstarPostParams
+		arg.UserID = user.ID
+		arg.PostID = postID
+		return s.db.UnstarPost(ctx, arg)
+	}
+	var arg database.StarPostParams
+	arg.UserID = user.ID
+	arg.PostID = postID
+	arg.StarredAt = time.Now()
+	return s.db.StarPost(ctx, arg)
+}
+
 func handleBrowse(s *state, cmd command, user database.User) error {
+	args, flags := parseFlags(cmd.args)
 	limit := 2
-	if len(cmd.args) > 0 {
-		arg, err := strconv.Atoi(cmd.args[0])
+	if len(args) > 0 {
+		arg, err := strconv.Atoi(args[0])
 		if err != nil {
 			fmt.Printf("ERROR: %v\n", err)
-			fmt.Println("Could not parse optional limit argument.\nUsage: gator browse [limit].  limit must be a decimal value.")
+			fmt.Println("Could not parse optional limit argument.\nUsage: gator browse [limit] [folder] [--unread] [--starred].  limit must be a decimal value.")
 			fmt.Println("Defaulting to limit= 2")
 		} else {
 			limit = arg
 		}
 	}
+	folder := ""
+	if len(args) > 1 {
+		folder = args[1]
+	}
 
-	posts, err := s.db.GetPostsForUser(context.Background(), user.ID)
+	var filter postFilter
+	filter.Folder = folder
+	filter.UnreadOnly = flags["unread"] == "true"
+	filter.StarredOnly = flags["starred"] == "true"
+	posts, err := postsForUser(s.ctx, s, user, filter)
 	if err != nil {
 		fmt.Printf("ERROR: Could not get posts for user %v\n", user.Name)
 		return err
@@ -330,6 +1135,15 @@ func handleBrowse(s *state, cmd command, user database.User) error {
 	fmt.Printf("Most recent %v posts for user %v\n\n", limit, user.Name)
 	for i := range limit {
 		// fmt.Printf("%v %v %v %v\n\n", posts[i].Title, posts[i].Url, posts[i].Description, posts[i].PublishedAt)
+		id := shortID(posts[i].ID)
+		if !posts[i].ReadAt.Valid {
+			id += " (unread)"
+		}
+		if posts[i].StarredAt.Valid {
+			id += " (starred)"
+		}
+		fmt.Println("ID:", id)
+		fmt.Println("FEED:", posts[i].FeedName)
 		fmt.Println("TITLE:", posts[i].Title)
 		fmt.Println("URL:", posts[i].Url)
 		fmt.Println("DESCRIPTION:", posts[i].Description)
@@ -339,76 +1153,138 @@ func handleBrowse(s *state, cmd command, user database.User) error {
 	return nil
 }
 
+func handleRead(s *state, cmd command, user database.User) error {
+	return markPosts(s, cmd, user, "read", func(postID uuid.UUID) error {
+		return setPostRead(s.ctx, s, user, postID, true)
+	})
+}
+
+func handleUnread(s *state, cmd command, user database.User) error {
+	return markPosts(s, cmd, user, "unread", func(postID uuid.UUID) error {
+		return setPostRead(s.ctx, s, user, postID, false)
+	})
+}
+
+func handleStar(s *state, cmd command, user database.User) error {
+	return markPosts(s, cmd, user, "starred", func(postID uuid.UUID) error {
+		return setPostStarred(s.ctx, s, user, postID, true)
+	})
+}
+
+func handleUnstar(s *state, cmd command, user database.User) error {
+	return markPosts(s, cmd, user, "unstarred", func(postID uuid.UUID) error {
+		return setPostStarred(s.ctx, s, user, postID, false)
+	})
+}
+
+// markPosts applies mark to every post named in cmd.args.  label is how
+// the change is described to the user.
+func markPosts(s *state, cmd command, user database.User, label string, mark func(postID uuid.UUID) error) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("ERROR: %v requires at least one post ID.\nUsage: gator %v <post> [post...]", cmd.name, cmd.name)
+	}
+	for _, ref := range cmd.args {
+		post, err := resolvePost(s, user, ref)
+		if err != nil {
+			return err
+		}
+		err = mark(post.ID)
+		if err != nil {
+			fmt.Printf("ERROR: Could not mark %v as %v.\n", post.Title, label)
+			return err
+		}
+		fmt.Printf("%v marked as %v.\n", post.Title, label)
+	}
+	return nil
+}
+
 func scrapeFeeds(s *state) error {
-	feedRow, err := s.db.GetNextFeedToFetch(context.Background())
+	feedRow, err := s.db.GetNextFeedToFetch(s.ctx)
 	if err != nil {
 		fmt.Println("ERROR: Could not retrieve feed data from database.")
 		return err
 	}
-	err = s.db.MarkFeedFetched(context.Background(), feedRow.ID)
+	err = s.db.MarkFeedFetched(s.ctx, feedRow.ID)
 	if err != nil {
 		fmt.Println("ERROR: Unable to mark feed as fetched.")
 		return err
 	}
-	feed, err := fetchFeed(context.Background(), feedRow.Url)
+	feed, err := fetchFeed(s.ctx, s.client, feedRow.Url)
 	if err != nil {
 		fmt.Printf("ERROR: Could not fetch feed from %v\n", feedRow.Url)
 		return err
 	}
 	fmt.Printf("Checking feed %v for new posts.\n\n", feed.Channel.Title)
-	count := 0
-	// fmt.Printf("Found %v posts.\n", len(feed.Channel.Item))
+	// A feed's posts are stored in one statement, all or nothing, so an
+	// error part way through is retried in full on the next fetch.
+	var arg database.CreatePostsParams
+	arg.FeedID = feedRow.ID
 	for _, item := range feed.Channel.Item {
 		published_at, err := dateparse.ParseAny(item.PubDate)
 		if err != nil {
-			fmt.Printf("ERROR: Could not parse PubDate for %v.\n", item.Link)
-		}
-		// fmt.Printf("%v\n", published_at)
-		var arg database.CreatePostParams
-		arg.ID = uuid.New()
-		arg.Title = item.Title
-		arg.Url = item.Link
-		arg.Description = item.Description
-		arg.PublishedAt = published_at
-		arg.FeedID = feedRow.ID
-		post, err := s.db.CreatePost(context.Background(), arg)
-		if err != nil {
-			// fmt.Println(item.Link)
-			err = checkPostError(err)
-			if err != nil {
-				return err
-			}
-
-		} else {
-			// fmt.Printf("%v %v %v %v\n\n", post.Title, post.Url, post.Description, post.PublishedAt)
-			fmt.Println("TITLE:", post.Title)
-			fmt.Println("URL:", post.Url)
-			fmt.Println("DESCRIPTION:", post.Description)
-			fmt.Println("PUBLISHED AT:", post.PublishedAt)
-			fmt.Println("")
-			count += 1
+			// A post without a date counts as published when it is first
+			// seen, so keep_days does not prune it straight away.
+			fmt.Printf("ERROR: Could not parse PubDate for %v, using the current time.\n", item.Link)
+			published_at = time.Now()
 		}
+		arg.Ids = append(arg.Ids, uuid.New())
+		arg.Titles = append(arg.Titles, item.Title)
+		arg.Urls = append(arg.Urls, item.Link)
+		arg.Descriptions = append(arg.Descriptions, item.Description)
+		arg.PublishedAts = append(arg.PublishedAts, published_at)
+	}
+	posts, err := s.db.CreatePosts(s.ctx, arg)
+	if err != nil {
+		fmt.Printf("ERROR: Could not store posts from %v\n", feedRow.Url)
+		return err
+	}
+	for _, post := range posts {
+		fmt.Println("TITLE:", post.Title)
+		fmt.Println("URL:", post.Url)
+		fmt.Println("DESCRIPTION:", post.Description)
+		fmt.Println("PUBLISHED AT:", post.PublishedAt)
+		fmt.Println("")
 	}
+	count := len(posts)
 	if count == 0 {
 		fmt.Printf("No new posts found.\n\n")
 	} else {
 		fmt.Printf("Found %v new posts.\n\n", count)
 	}
+	stored, err := s.db.GetFeed(s.ctx, feedRow.ID)
+	if err != nil {
+		fmt.Println("ERROR: Could not retrieve feed data from database.")
+		return err
+	}
+	pruned, err := pruneFeed(s.ctx, s, stored)
+	if err != nil {
+		fmt.Printf("ERROR: Could not prune old posts from %v\n", feedRow.Url)
+		return err
+	}
+	if pruned > 0 {
+		fmt.Printf("Pruned %v old posts.\n\n", pruned)
+	}
 	return nil
 }
 
-func checkPostError(err error) error {
-	if pqErr, ok := err.(*pq.Error); ok {
-		if pqErr.Constraint == "posts_url_key" {
-			return nil
-		}
+// isUniqueViolation reports whether err is a unique constraint violation,
+// like registering a name that is already taken.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
 	}
-	return err
+	var memErr *memdb.UniqueViolation
+	if errors.As(err, &memErr) {
+		return true
+	}
+	_, ok := sqlitedb.UniqueViolation(err)
+	return ok
 }
 
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
-		user, err := s.db.GetUser(context.Background(), s.cfg.Username)
+		user, err := currentUser(s.ctx, s)
 		if err != nil {
 			return err
 		}
@@ -416,51 +1292,162 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 	}
 }
 
+// middlewareAdmin is middlewareLoggedIn for commands that only admins may
+// run.
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+	return middlewareLoggedIn(func(s *state, cmd command, user database.User) error {
+		if user.Role != roleAdmin {
+			return fmt.Errorf("ERROR: \"gator %v\" can only be run by an admin.  User %v is not an admin.", cmd.name, user.Name)
+		}
+		return handler(s, cmd, user)
+	})
+}
+
 func handleHelp(_ *state, _ command) error {
 	fmt.Println("Gator - RSS Feed Aggregator")
 	fmt.Printf("See the README for more detailed usage examples.\n\n")
+	fmt.Println("Any command can be run as another user with --user=<username>, or GATOR_USER, and --token=<token>, or GATOR_TOKEN.  Without a token you are asked for the user's password.  The config file is not changed.")
+	fmt.Println("Feed fetches and database queries time out after http_timeout and db_timeout from the config file, 30s by default.  Ctrl+C stops a command cleanly, and a second Ctrl+C quits at once.")
+	fmt.Println("Commands that take a <feed> accept the feed's url, name, short ID, or a unique prefix of its name or ID.")
 	fmt.Println("Commands:")
 	fmt.Println("gator help: Displays this help message.")
-	fmt.Println("gator register <username>: registers <username> in the database and logs the user in.")
-	fmt.Println("gator login <username>: logs the user in if they are already registered.")
+	fmt.Println("gator migrate <up|down|status>: applies all pending schema migrations, rolls back the latest one after 'yes' confirmation, or lists which are applied.")
+	fmt.Println("gator register <username>: registers <username> in the database with a password and logs the user in.")
+	fmt.Println("gator login <username>: logs the user in after asking for their password.")
+	fmt.Println("gator passwd: changes the logged in user's password, and logs out the user's other sessions.")
+	fmt.Println("gator setpassword <username>: sets another user's password, for users registered before passwords existed or who forgot theirs.  Admin only, except to give the first admin a password while no user has one.")
+	fmt.Println("gator tokens: lists the logged in user's API and session tokens.")
+	fmt.Println("gator revoketoken <token>: revokes one of the logged in user's tokens, by ID or name.")
 	fmt.Println("gator users: lists all users.")
+	fmt.Println("gator setrole <username> <admin|user>: makes a user an admin or a regular user.  Admin only.")
+	fmt.Println("gator userinfo [username]: shows follows, unread counts and other stats for a user, the logged in user by default.")
+	fmt.Println("gator renameuser <username> <new_name>: renames the logged in user, or any user for admins.")
+	fmt.Println("gator deleteuser <username> [--transfer=<username>]: deletes the logged in user, or any user for admins, after 'yes' confirmation.  Feeds they added are kept, or given to the --transfer user.")
 	fmt.Println("gator addfeed <feed_name> <url>: adds the feed to the database and follows it for the logged in user.")
-	fmt.Println("gator feeds: lists all feeds in the database.")
-	fmt.Println("gator follow <url>: follows a feed already in the database.")
-	fmt.Println("gator following: lists all feeds followed by the logged in user.")
-	fmt.Println("gator unfollow <url>: unfollows the feed for the logged in user.")
-	fmt.Println("gator agg <interval>: Fetches posts from the feeds added with addfeed and stores them in the database.")
-	fmt.Println("gator browse [limit]: Optional limit value, defaults to 2. Lists [limit] number of posts from the logged in user's feeds, newest first.")
-	fmt.Println("gator reset: WARNING Deletes ALL data from the database after 'yes' confirmation. Use with caution.")
+	fmt.Println("gator feeds [search] [--sort=name|followers|posts|updated]: lists all feeds in the database with their short IDs, follower and post counts, and whether you follow them.  Optionally only feeds matching [search].")
+	fmt.Println("gator renamefeed <feed> <new_name>: renames a feed added by the logged in user.  Admins can rename any feed.")
+	fmt.Println("gator setfeedurl <feed> <new_url>: changes the url of a feed added by the logged in user.  Admins can change any feed.")
+	fmt.Println("gator setretention <feed> [--days=<n>|default] [--posts=<n>|default]: keeps a feed's posts for <n> days, or only its newest <n>, for a feed added by the logged in user.  Admins can change any feed.  0 keeps them forever, and default uses the config file's keep_days and keep_posts.  Without flags, shows the feed's retention.")
+	fmt.Println("gator deletefeed <feed>: deletes a feed added by the logged in user, or any feed for admins, along with its posts and follows, after 'yes' confirmation.")
+	fmt.Println("gator follow <feed>: follows a feed already in the database.")
+	fmt.Println("gator following [folder]: lists all feeds followed by the logged in user, or only those in [folder].")
+	fmt.Println("gator unfollow <feed>: unfollows the feed for the logged in user.")
+	fmt.Println("gator agg <interval> [--pidfile=<path>]: Fetches posts from the feeds added with addfeed and stores them in the database.  Only one agg runs per database.  Writes its pid to a pidfile next to a SQLite database, in ~ for Postgres, or to <path>, rereads the config file on SIGHUP, and on Ctrl+C or SIGTERM finishes the current fetch and exits.")
+	fmt.Println("gator prune [feed]: deletes posts past their feed's retention from every feed, or just [feed].  agg does this after each fetch.  Starred posts are kept, and pruned posts are not fetched again.  Admin only.")
+	fmt.Println("gator browse [limit] [folder] [--unread] [--starred]: Optional limit value, defaults to 2. Lists [limit] number of posts from the logged in user's feeds, newest first.  Optionally limited to feeds in [folder], or to unread or starred posts.")
+	fmt.Println("gator read <post> [post...]: marks posts as read, by the ID shown in browse.")
+	fmt.Println("gator unread <post> [post...]: marks posts as unread.")
+	fmt.Println("gator star <post> [post...]: stars posts.")
+	fmt.Println("gator unstar <post> [post...]: unstars posts.")
+	fmt.Println("gator newtoken [--feed] [name]: creates an API token for the logged in user, for use as a password in reader apps.  With --feed, the token only opens the user's Atom and RSS feeds.")
+	fmt.Println("gator feverkey: asks for the password the logged in user gives Fever apps, which only the Fever API accepts.")
+	fmt.Println("gator folders: lists the logged in user's folders.")
+	fmt.Println("gator addfolder <folder>: creates a folder for organizing followed feeds.")
+	fmt.Println("gator renamefolder <folder> <new_name>: renames a folder.")
+	fmt.Println("gator delfolder <folder>: deletes a folder.  Feeds in it stay followed.")
+	fmt.Println("gator setfolder <feed> [folder]: moves a followed feed into [folder], or out of any folder if omitted.")
+	fmt.Println("gator settitle <feed> [title]: shows the followed feed as [title] for the logged in user, or resets it to the feed name if omitted.")
+	fmt.Println("gator stats [--by=day|week] [--days=<n>] [--top=<n>] [--stale=<n>] [--json]: shows posts per feed per day or week over the last <n> days (30 by default), the <n> most active feeds (10), feeds with no posts in <n> days (30), read ratios and the database size.  Only admins see other users' read ratios.")
+	fmt.Println("gator serve [addr]: serves the web UI, JSON REST API, Atom/RSS feeds, Google Reader API and Fever API on [addr], defaults to :8080.")
+	fmt.Println("gator backup <file|->: writes all users, feeds, follows, folders, posts, and read and starred marks to a JSON archive, gzipped if <file> ends in .gz, or to stdout for -.  Admin only.")
+	fmt.Println("gator restore <file|->: loads a backup into an empty database, which can use a different backend from the one backed up.")
+	fmt.Println("gator reset [all|posts|fetch|user <username>] [--dry-run] [--yes]: WARNING Deletes ALL data from the database after 'yes' confirmation, or only every post, only feed fetch times, or only one user's follows, folders, read marks and stars.  --dry-run shows how many rows would be deleted, and --yes skips the confirmation.  Use with caution.  Admin only.")
 	return nil
 }
 
+// Defaults for http_timeout and db_timeout in the config file.
+const (
+	defaultHTTPTimeout = 30 * time.Second
+	defaultDBTimeout   = 30 * time.Second
+)
+
+// parseTimeout reads the timeout setting name from the config file, or
+// returns def if it is not set.
+func parseTimeout(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("ERROR: %v in the config file must be a duration like 30s, or 0 for no timeout, not %q", name, value)
+	}
+	return timeout, nil
+}
+
 func main() {
 	cfg := config.Read()
 	var s state
 	s.cfg = &cfg
-	db, err := sql.Open("postgres", cfg.DB_url)
+	httpTimeout, err := parseTimeout("http_timeout", cfg.HTTPTimeout, defaultHTTPTimeout)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	dbTimeout, err := parseTimeout("db_timeout", cfg.DBTimeout, defaultDBTimeout)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	backend, dbQueries, err := openBackend(cfg.DB_url, dbTimeout)
 	if err != nil {
 		fmt.Println("ERROR: Unable to connect to database:", err)
 		os.Exit(1)
 	}
-	dbQueries := database.New(db)
 	s.db = dbQueries
+	s.backend = backend
+	s.client = &http.Client{Timeout: httpTimeout}
+	// The first SIGINT or SIGTERM cancels s.ctx so the command can stop
+	// cleanly.  A second one kills gator as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	s.ctx = ctx
 	var cmds commands
 	cmds.funcs = make(map[string]func(*state, command) error)
 	cmds.register("login", handlerLogin)
 	cmds.register("register", handlerRegister)
-	cmds.register("reset", handlerReset)
+	cmds.register("reset", middlewareAdmin(handlerReset))
+	cmds.register("backup", middlewareAdmin(handleBackup))
+	cmds.register("restore", handleRestore)
 	cmds.register("users", handlerUsers)
 	cmds.register("agg", handleAgg)
 	cmds.register("addfeed", middlewareLoggedIn(handleAddfeed))
 	cmds.register("feeds", handleFeeds)
+	cmds.register("renamefeed", middlewareLoggedIn(handleRenamefeed))
+	cmds.register("setfeedurl", middlewareLoggedIn(handleSetfeedurl))
+	cmds.register("deletefeed", middlewareLoggedIn(handleDeletefeed))
+	cmds.register("setretention", middlewareLoggedIn(handleSetretention))
+	cmds.register("prune", middlewareAdmin(handlePrune))
 	cmds.register("follow", middlewareLoggedIn(handleFollow))
 	cmds.register("following", middlewareLoggedIn(handleFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(handleUnfollow))
 	cmds.register("browse", middlewareLoggedIn(handleBrowse))
+	cmds.register("folders", middlewareLoggedIn(handleFolders))
+	cmds.register("addfolder", middlewareLoggedIn(handleAddfolder))
+	cmds.register("renamefolder", middlewareLoggedIn(handleRenamefolder))
+	cmds.register("delfolder", middlewareLoggedIn(handleDelfolder))
+	cmds.register("setfolder", middlewareLoggedIn(handleSetfolder))
+	cmds.register("settitle", middlewareLoggedIn(handleSettitle))
+	cmds.register("read", middlewareLoggedIn(handleRead))
+	cmds.register("unread", middlewareLoggedIn(handleUnread))
+	cmds.register("star", middlewareLoggedIn(handleStar))
+	cmds.register("unstar", middlewareLoggedIn(handleUnstar))
+	cmds.register("newtoken", middlewareLoggedIn(handleNewtoken))
+	cmds.register("tokens", middlewareLoggedIn(handleTokens))
+	cmds.register("revoketoken", middlewareLoggedIn(handleRevoketoken))
+	cmds.register("passwd", middlewareLoggedIn(handlePasswd))
+	cmds.register("setpassword", handleSetpassword)
+	cmds.register("stats", middlewareLoggedIn(handleStats))
+	cmds.register("userinfo", middlewareLoggedIn(handleUserinfo))
+	cmds.register("renameuser", middlewareLoggedIn(handleRenameuser))
+	cmds.register("deleteuser", middlewareLoggedIn(handleDeleteuser))
+	cmds.register("setrole", middlewareAdmin(handleSetrole))
+	cmds.register("feverkey", middlewareLoggedIn(handleFeverkey))
+	cmds.register("serve", handleServe)
+	cmds.register("migrate", handleMigrate)
 	cmds.register("help", handleHelp)
-	argv := os.Args
+	s.asUser = os.Getenv("GATOR_USER")
+	s.asToken = os.Getenv("GATOR_TOKEN")
+	argv := globalFlags(&s, os.Args)
 	if len(argv) < 2 {
 		fmt.Println("Not enough arguemnts provided.")
 		os.Exit(1)
@@ -474,6 +1461,13 @@ func main() {
 	var cmd command
 	cmd.name = name
 	cmd.args = argv
+	if name != "migrate" && name != "help" {
+		err = checkSchema(&s)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 	err = cmds.run(&s, cmd)
 	if err != nil {
 		fmt.Println(err)
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lucoand/gator/internal/database"
+)
+
+func TestMarkPostNeedsFollow(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		alice, feed := addTestBlog(t, s)
+		bob := addTestUser(t, s, "bob")
+		post := addTestPosts(t, s, feed, time.Hour, "https://example.com/a")[0]
+
+		if err := setPostRead(s.ctx, s, alice, post.ID, true); err != nil {
+			t.Errorf("alice cannot mark a followed post read: %v", err)
+		}
+		if err := setPostStarred(s.ctx, s, alice, post.ID, true); err != nil {
+			t.Errorf("alice cannot star a followed post: %v", err)
+		}
+		if err := setPostRead(s.ctx, s, bob, post.ID, true); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("bob marking an unfollowed post read: got %v, want sql.ErrNoRows", err)
+		}
+		if err := setPostStarred(s.ctx, s, bob, post.ID, true); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("bob starring an unfollowed post: got %v, want sql.ErrNoRows", err)
+		}
+		impact, err := s.db.GetUserResetImpact(s.ctx, bob.ID)
+		if err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+		if impact.ReadCount != 0 || impact.StarCount != 0 {
+			t.Errorf("bob has %v reads and %v stars, want none", impact.ReadCount, impact.StarCount)
+		}
+	})
+}
+
+func TestFollowsSortedByTitle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		user := addTestUser(t, s, "alice")
+		addTestFeed(t, s, user, "Alpha", "https://alpha.example.com/feed")
+		beta := addTestFeed(t, s, user, "Beta", "https://beta.example.com/feed")
+		addTestFeed(t, s, user, "Gamma", "https://gamma.example.com/feed")
+		var arg database.SetFeedFollowTitleParams
+		arg.UserID = user.ID
+		arg.FeedID = beta.ID
+		arg.Title = sql.NullString{String: "Zeta", Valid: true}
+		if _, err := s.db.SetFeedFollowTitle(s.ctx, arg); err != nil {
+			t.Fatalf("set title: %v", err)
+		}
+		follows, err := s.db.GetFeedFollowsForUser(s.ctx, user.Name)
+		if err != nil {
+			t.Fatalf("get follows: %v", err)
+		}
+		var names []string
+		for _, follow := range follows {
+			names = append(names, follow.FeedName)
+		}
+		if want := []string{"Alpha", "Gamma", "Zeta"}; !slices.Equal(names, want) {
+			t.Errorf("follows in order %v, want %v", names, want)
+		}
+	})
+}
+
+func TestCreatePostsSkipsStoredPosts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		_, feed := addTestBlog(t, s)
+
+		posts := addTestPosts(t, s, feed, day, "https://example.com/a", "https://example.com/b")
+		if len(posts) != 2 {
+			t.Fatalf("first fetch stored %v posts, want 2", len(posts))
+		}
+		posts = addTestPosts(t, s, feed, day, "https://example.com/a", "https://example.com/b", "https://example.com/c")
+		if len(posts) != 1 || posts[0].Url != "https://example.com/c" {
+			t.Fatalf("second fetch stored %v, want only post c", posts)
+		}
+		if got := len(postURLs(t, s)); got != 3 {
+			t.Errorf("database has %v posts, want 3", got)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lucoand/gator/internal/migrate"
+)
+
+// handleMigrate applies or rolls back the schema migrations embedded in
+// the binary, or shows which of them are applied.
+func handleMigrate(s *state, cmd command) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("ERROR: migrate expects one argument.\nUsage: gator migrate <up|down|status>")
+	}
+	if s.backend.db == nil {
+		fmt.Printf("The %v database has no schema to migrate.\n", s.backend.name)
+		return nil
+	}
+	migrations, err := migrate.Load(s.backend.migrations)
+	if err != nil {
+		fmt.Println("ERROR: Could not load migrations.")
+		return err
+	}
+	ctx := s.ctx
+	switch cmd.args[0] {
+	case "up":
+		applied := 0
+		err = migrate.Up(ctx, s.backend.db, s.backend.dialect, migrations, func(m migrate.Migration) {
+			fmt.Printf("Applied %v\n", m.Name)
+			applied++
+		})
+		if err != nil {
+			fmt.Println("ERROR: Could not migrate database.")
+			return err
+		}
+		if applied == 0 {
+			fmt.Printf("Database schema is up to date at version %v.\n", migrate.Latest(migrations))
+		} else {
+			fmt.Printf("Database schema is now at version %v.\n", migrate.Latest(migrations))
+		}
+	case "down":
+		version, err := migrate.Version(ctx, s.backend.db, s.backend.dialect)
+		if err != nil {
+			fmt.Println("ERROR: Could not read schema version.")
+			return err
+		}
+		if version == 0 {
+			fmt.Println("No migrations are applied.")
+			return nil
+		}
+		if !confirm(ctx, fmt.Sprintf("This will roll back migration %v and may DELETE data.  Are you sure?", version)) {
+			fmt.Println("Migration cancelled.")
+			return nil
+		}
+		m, _, err := migrate.Down(ctx, s.backend.db, s.backend.dialect, migrations)
+		if err != nil {
+			fmt.Println("ERROR: Could not roll back migration.")
+			return err
+		}
+		fmt.Printf("Rolled back %v\n", m.Name)
+	case "status":
+		applied, err := migrate.Applied(ctx, s.backend.db, s.backend.dialect)
+		if err != nil {
+			fmt.Println("ERROR: Could not read schema version.")
+			return err
+		}
+		for _, m := range migrations {
+			status := "pending"
+			if applied[m.Version] {
+				status = "applied"
+			}
+			fmt.Printf("%-8v %v\n", status, m.Name)
+		}
+	default:
+		return fmt.Errorf("ERROR: unknown migrate action %v.\nUsage: gator migrate <up|down|status>", cmd.args[0])
+	}
+	return nil
+}
+
+// checkSchema makes sure the database has every migration this gator was
+// built with, so an old schema gives a clear error instead of failing
+// queries.
+func checkSchema(s *state) error {
+	if s.backend.db == nil {
+		return nil
+	}
+	migrations, err := migrate.Load(s.backend.migrations)
+	if err != nil {
+		return fmt.Errorf("ERROR: Could not load migrations: %w", err)
+	}
+	version, err := migrate.Version(s.ctx, s.backend.db, s.backend.dialect)
+	if err != nil {
+		return fmt.Errorf("ERROR: Unable to connect to database: %w", err)
+	}
+	latest := migrate.Latest(migrations)
+	switch {
+	case version < latest:
+		return fmt.Errorf("ERROR: Database schema is at version %v, but this gator needs version %v.\nRun \"gator migrate up\" to update it.", version, latest)
+	case version > latest:
+		return fmt.Errorf("ERROR: Database schema is at version %v, which is newer than this gator's version %v.\nUpdate gator, or run \"gator migrate down\" with the newer gator first.", version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+// queryTimeout is a database.Querier that cancels each query that runs for
+// longer than timeout.  The timeout is set per query rather than in the
+// DBTX, because a query's rows are read after QueryContext returns, and
+// the generated queries have always closed them by the time they return.
+// Every query is wrapped by hand, so one missing here does not compile.
+type queryTimeout struct {
+	db      database.Querier
+	timeout time.Duration
+}
+
+var _ database.Querier = queryTimeout{}
+
+// withQueryTimeout wraps db in a queryTimeout, unless timeout is 0.
+func withQueryTimeout(db database.Querier, timeout time.Duration) database.Querier {
+	if timeout == 0 {
+		return db
+	}
+	return queryTimeout{db: db, timeout: timeout}
+}
+
+func (q queryTimeout) ClearFeedFetchTimes(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.ClearFeedFetchTimes(ctx)
+}
+
+func (q queryTimeout) CountAdmins(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CountAdmins(ctx)
+}
+
+func (q queryTimeout) CountUsers(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CountUsers(ctx)
+}
+
+func (q queryTimeout) CreateAPIToken(ctx context.Context, arg database.CreateAPITokenParams) (database.ApiToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CreateAPIToken(ctx, arg)
+}
+
+func (q queryTimeout) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CreateFeed(ctx, arg)
+}
+
+func (q queryTimeout) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CreateFeedFollow(ctx, arg)
+}
+
+func (q queryTimeout) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CreateFolder(ctx, arg)
+}
+
+func (q queryTimeout) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CreatePost(ctx, arg)
+}
+
+func (q queryTimeout) CreatePosts(ctx context.Context, arg database.CreatePostsParams) ([]database.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CreatePosts(ctx, arg)
+}
+
+func (q queryTimeout) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.CreateUser(ctx, arg)
+}
+
+func (q queryTimeout) DeleteAPIToken(ctx context.Context, arg database.DeleteAPITokenParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteAPIToken(ctx, arg)
+}
+
+func (q queryTimeout) DeleteAPITokenByHash(ctx context.Context, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteAPITokenByHash(ctx, tokenHash)
+}
+
+func (q queryTimeout) DeleteAPITokensByName(ctx context.Context, arg database.DeleteAPITokensByNameParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteAPITokensByName(ctx, arg)
+}
+
+func (q queryTimeout) DeleteAllFeeds(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteAllFeeds(ctx)
+}
+
+func (q queryTimeout) DeleteAllPosts(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteAllPosts(ctx)
+}
+
+func (q queryTimeout) DeleteAllPrunedPosts(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteAllPrunedPosts(ctx)
+}
+
+func (q queryTimeout) DeleteAllUsers(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteAllUsers(ctx)
+}
+
+func (q queryTimeout) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteFeed(ctx, id)
+}
+
+func (q queryTimeout) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteFeedFollow(ctx, arg)
+}
+
+func (q queryTimeout) DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteFeedFollowsForUser(ctx, userID)
+}
+
+func (q queryTimeout) DeleteFolder(ctx context.Context, arg database.DeleteFolderParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteFolder(ctx, arg)
+}
+
+func (q queryTimeout) DeleteFoldersForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteFoldersForUser(ctx, userID)
+}
+
+func (q queryTimeout) DeletePostReadsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeletePostReadsForUser(ctx, userID)
+}
+
+func (q queryTimeout) DeletePostStarsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeletePostStarsForUser(ctx, userID)
+}
+
+func (q queryTimeout) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.DeleteUser(ctx, id)
+}
+
+func (q queryTimeout) GetAPITokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAPITokensForUser(ctx, userID)
+}
+
+func (q queryTimeout) GetAllAPITokens(ctx context.Context) ([]database.ApiToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllAPITokens(ctx)
+}
+
+func (q queryTimeout) GetAllFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllFeedFollows(ctx)
+}
+
+func (q queryTimeout) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllFeeds(ctx)
+}
+
+func (q queryTimeout) GetAllFolders(ctx context.Context) ([]database.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllFolders(ctx)
+}
+
+func (q queryTimeout) GetAllPostReads(ctx context.Context) ([]database.PostRead, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllPostReads(ctx)
+}
+
+func (q queryTimeout) GetAllPostStars(ctx context.Context) ([]database.PostStar, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllPostStars(ctx)
+}
+
+func (q queryTimeout) GetAllPosts(ctx context.Context) ([]database.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllPosts(ctx)
+}
+
+func (q queryTimeout) GetAllPrunedPosts(ctx context.Context) ([]database.PrunedPost, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetAllPrunedPosts(ctx)
+}
+
+func (q queryTimeout) GetDatabaseSize(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetDatabaseSize(ctx)
+}
+
+func (q queryTimeout) GetFeed(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeed(ctx, id)
+}
+
+func (q queryTimeout) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeedByUrl(ctx, url)
+}
+
+func (q queryTimeout) GetFeedCatalog(ctx context.Context, userID uuid.UUID) ([]database.GetFeedCatalogRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeedCatalog(ctx, userID)
+}
+
+func (q queryTimeout) GetFeedDeleteImpact(ctx context.Context, feedID uuid.UUID) (database.GetFeedDeleteImpactRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeedDeleteImpact(ctx, feedID)
+}
+
+func (q queryTimeout) GetFeedFollowsForUser(ctx context.Context, name string) ([]database.GetFeedFollowsForUserRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeedFollowsForUser(ctx, name)
+}
+
+func (q queryTimeout) GetFeedFollowsForUserInFolder(ctx context.Context, arg database.GetFeedFollowsForUserInFolderParams) ([]database.GetFeedFollowsForUserInFolderRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeedFollowsForUserInFolder(ctx, arg)
+}
+
+func (q queryTimeout) GetFeedIDByUrl(ctx context.Context, url string) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeedIDByUrl(ctx, url)
+}
+
+func (q queryTimeout) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFeeds(ctx)
+}
+
+func (q queryTimeout) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFolderByName(ctx, arg)
+}
+
+func (q queryTimeout) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetFoldersForUser(ctx, userID)
+}
+
+func (q queryTimeout) GetNextFeedToFetch(ctx context.Context) (database.GetNextFeedToFetchRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetNextFeedToFetch(ctx)
+}
+
+func (q queryTimeout) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetPost(ctx, id)
+}
+
+func (q queryTimeout) GetPostByNum(ctx context.Context, num int64) (database.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetPostByNum(ctx, num)
+}
+
+func (q queryTimeout) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetPostForUser(ctx, arg)
+}
+
+func (q queryTimeout) GetPostCountsByDay(ctx context.Context, since time.Time) ([]database.GetPostCountsByDayRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetPostCountsByDay(ctx, since)
+}
+
+func (q queryTimeout) GetPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetPostsForUserRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetPostsForUser(ctx, userID)
+}
+
+func (q queryTimeout) GetPostsForUserInFolder(ctx context.Context, arg database.GetPostsForUserInFolderParams) ([]database.GetPostsForUserInFolderRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetPostsForUserInFolder(ctx, arg)
+}
+
+func (q queryTimeout) GetReadRatios(ctx context.Context) ([]database.GetReadRatiosRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetReadRatios(ctx)
+}
+
+func (q queryTimeout) GetResetImpact(ctx context.Context) (database.GetResetImpactRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetResetImpact(ctx)
+}
+
+func (q queryTimeout) GetUser(ctx context.Context, name string) (database.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetUser(ctx, name)
+}
+
+func (q queryTimeout) GetUserByTokenHash(ctx context.Context, arg database.GetUserByTokenHashParams) (database.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetUserByTokenHash(ctx, arg)
+}
+
+func (q queryTimeout) GetUserResetImpact(ctx context.Context, userID uuid.UUID) (database.GetUserResetImpactRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetUserResetImpact(ctx, userID)
+}
+
+func (q queryTimeout) GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetUserStats(ctx, userID)
+}
+
+func (q queryTimeout) GetUsers(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.GetUsers(ctx)
+}
+
+func (q queryTimeout) ListUsers(ctx context.Context) ([]database.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.ListUsers(ctx)
+}
+
+func (q queryTimeout) LockUsers(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.LockUsers(ctx)
+}
+
+func (q queryTimeout) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.MarkFeedFetched(ctx, id)
+}
+
+func (q queryTimeout) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.MarkPostRead(ctx, arg)
+}
+
+func (q queryTimeout) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.MarkPostUnread(ctx, arg)
+}
+
+func (q queryTimeout) PrunePosts(ctx context.Context, arg database.PrunePostsParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.PrunePosts(ctx, arg)
+}
+
+func (q queryTimeout) RenameFeed(ctx context.Context, arg database.RenameFeedParams) (database.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RenameFeed(ctx, arg)
+}
+
+func (q queryTimeout) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (database.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RenameFolder(ctx, arg)
+}
+
+func (q queryTimeout) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RenameUser(ctx, arg)
+}
+
+func (q queryTimeout) ResetNumSequences(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.ResetNumSequences(ctx)
+}
+
+func (q queryTimeout) RestoreAPIToken(ctx context.Context, arg database.RestoreAPITokenParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RestoreAPIToken(ctx, arg)
+}
+
+func (q queryTimeout) RestoreFeed(ctx context.Context, arg database.RestoreFeedParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RestoreFeed(ctx, arg)
+}
+
+func (q queryTimeout) RestoreFeedFollow(ctx context.Context, arg database.RestoreFeedFollowParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RestoreFeedFollow(ctx, arg)
+}
+
+func (q queryTimeout) RestoreFolder(ctx context.Context, arg database.RestoreFolderParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RestoreFolder(ctx, arg)
+}
+
+func (q queryTimeout) RestorePost(ctx context.Context, arg database.RestorePostParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RestorePost(ctx, arg)
+}
+
+func (q queryTimeout) RestorePrunedPost(ctx context.Context, arg database.RestorePrunedPostParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.RestorePrunedPost(ctx, arg)
+}
+
+func (q queryTimeout) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.SetFeedFollowFolder(ctx, arg)
+}
+
+func (q queryTimeout) SetFeedFollowTitle(ctx context.Context, arg database.SetFeedFollowTitleParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.SetFeedFollowTitle(ctx, arg)
+}
+
+func (q queryTimeout) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.SetFeedRetention(ctx, arg)
+}
+
+func (q queryTimeout) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.SetUserPassword(ctx, arg)
+}
+
+func (q queryTimeout) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (database.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.SetUserRole(ctx, arg)
+}
+
+func (q queryTimeout) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.StarPost(ctx, arg)
+}
+
+func (q queryTimeout) TouchAPIToken(ctx context.Context, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.TouchAPIToken(ctx, tokenHash)
+}
+
+func (q queryTimeout) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.TransferFeeds(ctx, arg)
+}
+
+func (q queryTimeout) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.UnstarPost(ctx, arg)
+}
+
+func (q queryTimeout) UpdateFeedUrl(ctx context.Context, arg database.UpdateFeedUrlParams) (database.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	return q.db.UpdateFeedUrl(ctx, arg)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucoand/gator/internal/database"
+)
+
+// ctxQuerier records the context CountUsers runs under, and waits for it
+// to be done if slow is set.
+type ctxQuerier struct {
+	database.Querier
+	slow bool
+	ctx  context.Context
+}
+
+func (q *ctxQuerier) CountUsers(ctx context.Context) (int64, error) {
+	q.ctx = ctx
+	if q.slow {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	return 1, nil
+}
+
+func TestQueryTimeout(t *testing.T) {
+	slow := &ctxQuerier{slow: true}
+	_, err := withQueryTimeout(slow, 10*time.Millisecond).CountUsers(t.Context())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow query returned %v, want context.DeadlineExceeded", err)
+	}
+
+	// A query that finishes releases its timeout straight away.
+	fast := &ctxQuerier{}
+	if _, err := withQueryTimeout(fast, time.Hour).CountUsers(t.Context()); err != nil {
+		t.Fatalf("fast query: %v", err)
+	}
+	if fast.ctx.Err() == nil {
+		t.Error("query context still live after the query returned")
+	}
+
+	if db := withQueryTimeout(fast, 0); db != database.Querier(fast) {
+		t.Error("a zero timeout wrapped the queries")
+	}
+}
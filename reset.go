@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+const resetUsage = "Usage: gator reset [all|posts|fetch|user <username>] [--dry-run] [--yes]"
+
+// resetCount is one kind of row a reset deletes or changes, and how many
+// of them there are.
+type resetCount struct {
+	count int64
+	what  string
+}
+
+// resetPlan is what a reset will do: the rows it touches, and the
+// deletes that do it.
+type resetPlan struct {
+	summary string
+	counts  []resetCount
+	run     func(ctx context.Context, tx *state) error
+}
+
+// handlerReset deletes everything, or the part of the database named by
+// its scope.  --dry-run only counts what would be deleted, and --yes
+// skips the confirmation, for scripts.
+func handlerReset(s *state, cmd command, _ database.User) error {
+	args, flags := parseFlags(cmd.args)
+	scope := "all"
+	if len(args) > 0 {
+		scope = args[0]
+	}
+	ctx := s.ctx
+	var plan resetPlan
+	var err error
+	switch scope {
+	case "all":
+		plan, err = planResetAll(ctx, s)
+	case "posts":
+		plan, err = planResetPosts(ctx, s)
+	case "fetch":
+		plan, err = planResetFetch(ctx, s)
+	case "user":
+		if len(args) != 2 {
+			return fmt.Errorf("ERROR: reset user requires a username.\n%v", resetUsage)
+		}
+		plan, err = planResetUser(ctx, s, args[1])
+	default:
+		return fmt.Errorf("ERROR: Unknown reset scope %v.\n%v", scope, resetUsage)
+	}
+	if err != nil {
+		return err
+	}
+	if flags["dry-run"] == "true" {
+		fmt.Printf("Dry run: reset %v would delete or change:\n", scope)
+		printResetCounts(plan.counts)
+		return nil
+	}
+	fmt.Printf("This will %v:\n", plan.summary)
+	printResetCounts(plan.counts)
+	if flags["yes"] != "true" && !confirm(ctx, "Are you sure?") {
+		fmt.Println("Aborted.")
+		return nil
+	}
+	err = withTx(ctx, s, func(tx *state) error {
+		return plan.run(ctx, tx)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Reset complete.")
+	return nil
+}
+
+func printResetCounts(counts []resetCount) {
+	for _, c := range counts {
+		fmt.Printf("  %v %v\n", c.count, c.what)
+	}
+}
+
+func planResetAll(ctx context.Context, s *state) (resetPlan, error) {
+	impact, err := s.db.GetResetImpact(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not count rows in database.")
+		return resetPlan{}, err
+	}
+	return resetPlan{
+		summary: "DELETE ALL data from the database, including user and feed data",
+		counts: []resetCount{
+			{impact.UserCount, "user(s)"},
+			{impact.TokenCount, "token(s)"},
+			{impact.FeedCount, "feed(s)"},
+			{impact.FollowCount, "follow(s)"},
+			{impact.FolderCount, "folder(s)"},
+			{impact.PostCount, "post(s)"},
+			{impact.ReadCount, "read mark(s)"},
+			{impact.StarCount, "star(s)"},
+			{impact.PrunedPostCount, "pruned post url(s)"},
+		},
+		run: func(ctx context.Context, tx *state) error {
+			err := tx.db.DeleteAllUsers(ctx)
+			if err != nil {
+				fmt.Println("ERROR: Could not reset users table.")
+				return err
+			}
+			// Feeds outlive their users, so they have to be deleted separately.
+			err = tx.db.DeleteAllFeeds(ctx)
+			if err != nil {
+				fmt.Println("ERROR: Could not reset feeds table.")
+				return err
+			}
+			return nil
+		},
+	}, nil
+}
+
+func planResetPosts(ctx context.Context, s *state) (resetPlan, error) {
+	impact, err := s.db.GetResetImpact(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not count rows in database.")
+		return resetPlan{}, err
+	}
+	return resetPlan{
+		summary: "delete every post, with its read and starred marks, and forget pruned posts so they can be fetched again",
+		counts: []resetCount{
+			{impact.PostCount, "post(s)"},
+			{impact.ReadCount, "read mark(s)"},
+			{impact.StarCount, "star(s)"},
+			{impact.PrunedPostCount, "pruned post url(s)"},
+		},
+		run: func(ctx context.Context, tx *state) error {
+			_, err := tx.db.DeleteAllPosts(ctx)
+			if err != nil {
+				fmt.Println("ERROR: Could not reset posts table.")
+				return err
+			}
+			_, err = tx.db.DeleteAllPrunedPosts(ctx)
+			if err != nil {
+				fmt.Println("ERROR: Could not reset pruned posts table.")
+				return err
+			}
+			return nil
+		},
+	}, nil
+}
+
+func planResetFetch(ctx context.Context, s *state) (resetPlan, error) {
+	impact, err := s.db.GetResetImpact(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not count rows in database.")
+		return resetPlan{}, err
+	}
+	return resetPlan{
+		summary: "mark every feed as never fetched, so agg fetches them all again first",
+		counts: []resetCount{
+			{impact.FetchedFeedCount, "feed fetch time(s)"},
+		},
+		run: func(ctx context.Context, tx *state) error {
+			_, err := tx.db.ClearFeedFetchTimes(ctx)
+			if err != nil {
+				fmt.Println("ERROR: Could not reset feed fetch times.")
+			}
+			return err
+		},
+	}, nil
+}
+
+// planResetUser clears what a user has done, but keeps the user, their
+// password and tokens, and the feeds they added.  deleteuser removes the
+// user too.
+func planResetUser(ctx context.Context, s *state, name string) (resetPlan, error) {
+	user, err := s.db.GetUser(ctx, name)
+	if err != nil {
+		fmt.Printf("ERROR: User %v not found.\n", name)
+		return resetPlan{}, err
+	}
+	impact, err := s.db.GetUserResetImpact(ctx, user.ID)
+	if err != nil {
+		fmt.Println("ERROR: Could not count rows in database.")
+		return resetPlan{}, err
+	}
+	return resetPlan{
+		summary: fmt.Sprintf("delete the follows, folders, read marks and stars of user %v", user.Name),
+		counts: []resetCount{
+			{impact.FollowCount, "follow(s)"},
+			{impact.FolderCount, "folder(s)"},
+			{impact.ReadCount, "read mark(s)"},
+			{impact.StarCount, "star(s)"},
+		},
+		run: func(ctx context.Context, tx *state) error {
+			deletes := []struct {
+				what   string
+				delete func(context.Context, uuid.UUID) (int64, error)
+			}{
+				{"follows", tx.db.DeleteFeedFollowsForUser},
+				{"folders", tx.db.DeleteFoldersForUser},
+				{"read marks", tx.db.DeletePostReadsForUser},
+				{"stars", tx.db.DeletePostStarsForUser},
+			}
+			for _, d := range deletes {
+				_, err := d.delete(ctx, user.ID)
+				if err != nil {
+					fmt.Printf("ERROR: Could not delete %v of user %v.\n", d.what, user.Name)
+					return err
+				}
+			}
+			return nil
+		},
+	}, nil
+}
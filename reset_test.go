@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucoand/gator/internal/database"
+)
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		args []string
+		// want is what GetResetImpact counts after the reset, from
+		// addTestData's 2 users, 2 tokens, 2 feeds (1 fetched), 3
+		// follows, 1 folder, 3 posts, 2 reads, 2 stars and 1 pruned post.
+		want database.GetResetImpactRow
+	}{
+		{
+			args: []string{"--dry-run"},
+			want: database.GetResetImpactRow{UserCount: 2, TokenCount: 2, FeedCount: 2, FetchedFeedCount: 1, FollowCount: 3, FolderCount: 1, PostCount: 3, ReadCount: 2, StarCount: 2, PrunedPostCount: 1},
+		},
+		{
+			args: []string{"all", "--yes"},
+			want: database.GetResetImpactRow{},
+		},
+		{
+			args: []string{"posts", "--yes"},
+			want: database.GetResetImpactRow{UserCount: 2, TokenCount: 2, FeedCount: 2, FetchedFeedCount: 1, FollowCount: 3, FolderCount: 1},
+		},
+		{
+			args: []string{"fetch", "--yes"},
+			want: database.GetResetImpactRow{UserCount: 2, TokenCount: 2, FeedCount: 2, FollowCount: 3, FolderCount: 1, PostCount: 3, ReadCount: 2, StarCount: 2, PrunedPostCount: 1},
+		},
+		{
+			// alice has one follow, the folder, one read and one star.
+			args: []string{"user", "alice", "--yes"},
+			want: database.GetResetImpactRow{UserCount: 2, TokenCount: 2, FeedCount: 2, FetchedFeedCount: 1, FollowCount: 2, PostCount: 3, ReadCount: 1, StarCount: 1, PrunedPostCount: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args[0], func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, s *state) {
+				data := addTestData(t, s)
+				err := handlerReset(s, command{name: "reset", args: tt.args}, data.alice)
+				if err != nil {
+					t.Fatalf("reset: %v", err)
+				}
+				impact, err := s.db.GetResetImpact(s.ctx)
+				if err != nil {
+					t.Fatalf("count rows: %v", err)
+				}
+				if impact != tt.want {
+					t.Errorf("after reset %v:\ngot  %+v\nwant %+v", tt.args, impact, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestResetUserKeepsOtherUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		data := addTestData(t, s)
+		err := handlerReset(s, command{name: "reset", args: []string{"user", "alice", "--yes"}}, data.alice)
+		if err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		alice, err := s.db.GetUserResetImpact(s.ctx, data.alice.ID)
+		if err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+		if alice != (database.GetUserResetImpactRow{}) {
+			t.Errorf("alice still has %+v", alice)
+		}
+		bob, err := s.db.GetUserResetImpact(s.ctx, data.bob.ID)
+		if err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+		want := database.GetUserResetImpactRow{FollowCount: 2, ReadCount: 1, StarCount: 1}
+		if bob != want {
+			t.Errorf("bob has %+v, want %+v", bob, want)
+		}
+		// alice and her token are kept.
+		if _, err := userForToken(s.ctx, s, "alice-token"); err != nil {
+			t.Errorf("alice's token stopped working: %v", err)
+		}
+	})
+}
+
+func TestResetUnknownUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		data := addTestData(t, s)
+		err := handlerReset(s, command{name: "reset", args: []string{"user", "carol", "--yes"}}, data.alice)
+		if err == nil {
+			t.Error("reset user carol succeeded for a user that does not exist")
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lucoand/gator/internal/database"
+)
+
+// retention is how long feed's posts are kept: the feed's own keep_days
+// and keep_posts, or the config file's where the feed has none.  Zero is
+// no limit.
+func retention(s *state, feed database.Feed) (days int, posts int) {
+	days = s.cfg.KeepDays
+	if feed.KeepDays.Valid {
+		days = int(feed.KeepDays.Int32)
+	}
+	posts = s.cfg.KeepPosts
+	if feed.KeepPosts.Valid {
+		posts = int(feed.KeepPosts.Int32)
+	}
+	return days, posts
+}
+
+// describeRetention puts a feed's retention into words, to follow "kept",
+// like "for 30 days, up to the newest 100".
+func describeRetention(days, posts int) string {
+	switch {
+	case days > 0 && posts > 0:
+		return fmt.Sprintf("for %v days, up to the newest %v", days, posts)
+	case days > 0:
+		return fmt.Sprintf("for %v days", days)
+	case posts > 0:
+		return fmt.Sprintf("up to the newest %v", posts)
+	}
+	return "forever"
+}
+
+// pruneFeed deletes the posts of feed that are past its retention, apart
+// from starred ones, and returns how many were deleted.  Their urls are
+// remembered, so agg does not store them again.
+func pruneFeed(ctx context.Context, s *state, feed database.Feed) (int64, error) {
+	days, posts := retention(s, feed)
+	if days == 0 && posts == 0 {
+		return 0, nil
+	}
+	var arg database.PrunePostsParams
+	arg.FeedID = feed.ID
+	if days > 0 {
+		arg.PublishedBefore = sql.NullTime{Time: time.Now().AddDate(0, 0, -days), Valid: true}
+	}
+	if posts > 0 {
+		arg.KeepPosts = sql.NullInt32{Int32: int32(posts), Valid: true}
+	}
+	var pruned int64
+	// On SQLite this is two statements, storing the urls and deleting
+	// the posts, which must not be split.
+	err := withTx(ctx, s, func(tx *state) error {
+		var err error
+		pruned, err = tx.db.PrunePosts(ctx, arg)
+		return err
+	})
+	return pruned, err
+}
+
+func handlePrune(s *state, cmd command, _ database.User) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf("ERROR: prune takes at most one argument.\nUsage: gator prune [feed]")
+	}
+	ctx := s.ctx
+	var feeds []database.Feed
+	if len(cmd.args) == 1 {
+		feed, err := resolveFeed(s, cmd.args[0])
+		if err != nil {
+			return err
+		}
+		feeds = append(feeds, feed)
+	} else {
+		var err error
+		feeds, err = s.db.GetAllFeeds(ctx)
+		if err != nil {
+			fmt.Println("ERROR: Could not retrieve feeds from database.")
+			return err
+		}
+	}
+	var total int64
+	for _, feed := range feeds {
+		pruned, err := pruneFeed(ctx, s, feed)
+		if err != nil {
+			fmt.Printf("ERROR: Could not prune posts from %v.\n", feed.Name)
+			return err
+		}
+		if pruned > 0 {
+			fmt.Printf("Pruned %v post(s) from %v.\n", pruned, feed.Name)
+		}
+		total += pruned
+	}
+	fmt.Printf("Pruned %v post(s) in total.\n", total)
+	return nil
+}
+
+// parseRetention reads a --days or --posts value for setretention:
+// "default" for the config file's setting, or a number, where 0 keeps
+// posts forever.
+func parseRetention(name, value string) (sql.NullInt32, error) {
+	if value == "default" {
+		return sql.NullInt32{}, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 0 {
+		return sql.NullInt32{}, fmt.Errorf("ERROR: --%v must be a number of at least 0, or \"default\".\nUsage: gator setretention <feed> [--days=<n>|default] [--posts=<n>|default]", name)
+	}
+	return sql.NullInt32{Int32: int32(n), Valid: true}, nil
+}
+
+func handleSetretention(s *state, cmd command, user database.User) error {
+	args, flags := parseFlags(cmd.args)
+	if len(args) != 1 {
+		return fmt.Errorf("ERROR: setretention requires a feed.\nUsage: gator setretention <feed> [--days=<n>|default] [--posts=<n>|default]")
+	}
+	feed, err := getOwnedFeed(s, user, args[0])
+	if err != nil {
+		return err
+	}
+	days, setDays := flags["days"]
+	posts, setPosts := flags["posts"]
+	if !setDays && !setPosts {
+		fmt.Printf("Posts from %v are kept %v.\n", feed.Name, describeRetention(retention(s, feed)))
+		return nil
+	}
+	var arg database.SetFeedRetentionParams
+	arg.ID = feed.ID
+	arg.KeepDays = feed.KeepDays
+	arg.KeepPosts = feed.KeepPosts
+	if setDays {
+		arg.KeepDays, err = parseRetention("days", days)
+		if err != nil {
+			return err
+		}
+	}
+	if setPosts {
+		arg.KeepPosts, err = parseRetention("posts", posts)
+		if err != nil {
+			return err
+		}
+	}
+	updated, err := s.db.SetFeedRetention(s.ctx, arg)
+	if err != nil {
+		fmt.Println("ERROR: Could not set feed retention.")
+		return err
+	}
+	fmt.Printf("Posts from %v are now kept %v.\n", updated.Name, describeRetention(retention(s, updated)))
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lucoand/gator/internal/database"
+)
+
+func TestCreatePostsSkipsPrunedPosts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		_, feed := addTestBlog(t, s)
+		addTestPosts(t, s, feed, 2*day, "https://example.com/old")
+		addTestPosts(t, s, feed, day, "https://example.com/new")
+
+		s.cfg.KeepPosts = 1
+		pruned, err := pruneFeed(s.ctx, s, feed)
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if pruned != 1 {
+			t.Fatalf("pruned %v posts, want 1", pruned)
+		}
+		// The feed still lists the old post, but it is not stored again.
+		posts := addTestPosts(t, s, feed, 2*day, "https://example.com/old", "https://example.com/newer")
+		if len(posts) != 1 || posts[0].Url != "https://example.com/newer" {
+			t.Fatalf("fetch after prune stored %v, want only post newer", posts)
+		}
+	})
+}
+
+func TestPruneFeed(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepDays  int
+		keepPosts int
+		starOld   bool
+		want      []string
+	}{
+		{name: "keep forever", want: []string{"new", "week", "month"}},
+		{name: "keep posts", keepPosts: 2, want: []string{"new", "week"}},
+		{name: "keep days", keepDays: 10, want: []string{"new", "week"}},
+		{name: "keep both", keepDays: 10, keepPosts: 1, want: []string{"new"}},
+		{name: "starred kept", keepPosts: 1, starOld: true, want: []string{"new", "month"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, s *state) {
+				user, feed := addTestBlog(t, s)
+				addTestPosts(t, s, feed, time.Hour, "new")
+				addTestPosts(t, s, feed, 7*day, "week")
+				old := addTestPosts(t, s, feed, 30*day, "month")
+				if tt.starOld {
+					starTestPost(t, s, user, old[0])
+				}
+
+				s.cfg.KeepDays = tt.keepDays
+				s.cfg.KeepPosts = tt.keepPosts
+				pruned, err := pruneFeed(s.ctx, s, feed)
+				if err != nil {
+					t.Fatalf("prune: %v", err)
+				}
+				got := postURLs(t, s)
+				slices.Sort(got)
+				want := slices.Clone(tt.want)
+				slices.Sort(want)
+				if !slices.Equal(got, want) {
+					t.Errorf("posts kept = %v, want %v", got, want)
+				}
+				if wantPruned := int64(3 - len(tt.want)); pruned != wantPruned {
+					t.Errorf("pruned %v posts, want %v", pruned, wantPruned)
+				}
+				prunedPosts, err := s.db.GetAllPrunedPosts(s.ctx)
+				if err != nil {
+					t.Fatalf("get pruned posts: %v", err)
+				}
+				if int64(len(prunedPosts)) != pruned {
+					t.Errorf("remembered %v pruned urls, want %v", len(prunedPosts), pruned)
+				}
+			})
+		})
+	}
+}
+
+func TestPruneFeedOverridesConfig(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		_, feed := addTestBlog(t, s)
+		addTestPosts(t, s, feed, time.Hour, "new")
+		addTestPosts(t, s, feed, 30*day, "month")
+
+		// A feed kept forever is not pruned by the config's keep_days.
+		s.cfg.KeepDays = 10
+		var arg database.SetFeedRetentionParams
+		arg.ID = feed.ID
+		arg.KeepDays.Valid = true
+		feed, err := s.db.SetFeedRetention(s.ctx, arg)
+		if err != nil {
+			t.Fatalf("set retention: %v", err)
+		}
+		pruned, err := pruneFeed(s.ctx, s, feed)
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if pruned != 0 {
+			t.Errorf("pruned %v posts of a feed kept forever", pruned)
+		}
+	})
+}
+
+func TestScrapeFeedsDatesUndatedPosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Blog</title>
+<item><title>Undated</title><link>https://example.com/undated</link></item>
+<item><title>Old</title><link>https://example.com/old</link><pubDate>Mon, 01 Jan 2001 00:00:00 GMT</pubDate></item>
+</channel></rss>`)
+	}))
+	defer server.Close()
+	forEachBackend(t, func(t *testing.T, s *state) {
+		user := addTestUser(t, s, "alice")
+		addTestFeed(t, s, user, "Blog", server.URL)
+		s.cfg.KeepDays = 30
+		if err := scrapeFeeds(s); err != nil {
+			t.Fatalf("scrape: %v", err)
+		}
+		// The post without a date is kept as new, and only the old one is
+		// pruned.
+		if got := postURLs(t, s); !slices.Equal(got, []string{"https://example.com/undated"}) {
+			t.Errorf("posts kept = %v, want only the undated post", got)
+		}
+	})
+}
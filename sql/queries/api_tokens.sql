@@ -0,0 +1,42 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, scope)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
+	)
+	RETURNING *;
+
+-- name: GetUserByTokenHash :one
+SELECT users.* FROM users
+INNER JOIN api_tokens
+ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = $1
+AND api_tokens.scope = $2;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE token_hash = $1;
+
+-- name: DeleteAPITokensByName :execrows
+DELETE FROM api_tokens
+WHERE user_id = $1
+AND name = $2;
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1
+AND user_id = $2;
+
+-- name: DeleteAPITokenByHash :exec
+DELETE FROM api_tokens
+WHERE token_hash = $1;
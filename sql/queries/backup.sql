@@ -0,0 +1,107 @@
+-- name: GetAllAPITokens :many
+SELECT * FROM api_tokens
+ORDER BY created_at, id;
+
+-- name: GetAllFolders :many
+SELECT * FROM folders
+ORDER BY num;
+
+-- name: GetAllFeedFollows :many
+SELECT * FROM feed_follows
+ORDER BY created_at, id;
+
+-- name: GetAllPosts :many
+SELECT * FROM posts
+ORDER BY num;
+
+-- name: GetAllPostReads :many
+SELECT * FROM post_reads
+ORDER BY read_at, user_id, post_id;
+
+-- name: GetAllPostStars :many
+SELECT * FROM post_stars
+ORDER BY starred_at, user_id, post_id;
+
+-- name: GetAllPrunedPosts :many
+SELECT * FROM pruned_posts
+ORDER BY pruned_at, url;
+
+-- name: RestoreAPIToken :exec
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, last_used_at, scope)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7
+	);
+
+-- name: RestoreFeed :exec
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9,
+	$10
+	);
+
+-- name: RestoreFolder :exec
+INSERT INTO folders (id, created_at, updated_at, name, user_id, num)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
+	);
+
+-- name: RestoreFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id, title)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7
+	);
+
+-- name: RestorePost :exec
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, num)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9
+	);
+
+-- name: RestorePrunedPost :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+VALUES (
+	$1,
+	$2,
+	$3
+	);
+
+-- name: ResetNumSequences :exec
+-- Restored rows bring their own nums, so the sequences are moved past
+-- them for the rows created after.
+SELECT
+	setval(pg_get_serial_sequence('feeds', 'num'), COALESCE((SELECT MAX(num) FROM feeds), 0) + 1, false),
+	setval(pg_get_serial_sequence('folders', 'num'), COALESCE((SELECT MAX(num) FROM folders), 0) + 1, false),
+	setval(pg_get_serial_sequence('posts', 'num'), COALESCE((SELECT MAX(num) FROM posts), 0) + 1, false);
@@ -22,25 +22,48 @@ ON users.id = inserted_feed_follow.user_id;
 
 -- name: GetFeedFollowsForUser :many
 SELECT
-	feeds.name AS feed_name,
-	users.name as user_name
+	feeds.id AS feed_id,
+	feeds.url AS feed_url,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	users.name as user_name,
+	folders.name AS folder_name,
+	feeds.num AS feed_num,
+	folders.num AS folder_num,
+	feeds.last_fetched_at AS last_fetched_at
 FROM feed_follows
 INNER JOIN feeds
 ON feeds.id = feed_follows.feed_id
 INNER JOIN users
 ON users.id = feed_follows.user_id
-WHERE users.name = $1;
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+WHERE users.name = $1
+ORDER BY folders.name NULLS FIRST, COALESCE(feed_follows.title, feeds.name);
 
--- name: DeleteFeedFollowByUserNameAndFeedUrl :one
--- @param user_name: string
--- @param feed_url: string
-WITH deleted AS (
+-- name: GetFeedFollowsForUserInFolder :many
+SELECT
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND feed_follows.folder_id = $2
+ORDER BY COALESCE(feed_follows.title, feeds.name);
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3, updated_at = NOW()
+WHERE user_id = $1
+AND feed_id = $2;
+
+-- name: DeleteFeedFollow :execrows
 DELETE FROM feed_follows
-USING users, feeds
-WHERE users.id = feed_follows.user_id
-AND feeds.id = feed_follows.feed_id
-AND users.name = sqlc.arg('user_name')
-AND feeds.url = sqlc.arg('feed_url')
-RETURNING 1
-)
-SELECT COUNT(*) FROM deleted;
+WHERE user_id = $1
+AND feed_id = $2;
+
+-- name: SetFeedFollowTitle :execrows
+UPDATE feed_follows
+SET title = $3, updated_at = NOW()
+WHERE user_id = $1
+AND feed_id = $2;
@@ -11,11 +11,32 @@ VALUES (
 	RETURNING *;
 
 -- name: GetFeeds :many
-SELECT feeds.name AS name, feeds.url AS url, users.name AS user_name
+SELECT feeds.id AS id, feeds.name AS name, feeds.url AS url, users.name AS user_name
 FROM feeds 
-INNER JOIN users
+LEFT JOIN users
 ON feeds.user_id = users.id;
 
+-- name: GetFeedCatalog :many
+-- @param user_id: uuid
+SELECT
+	feeds.id AS id,
+	feeds.name AS name,
+	feeds.url AS url,
+	users.name AS user_name,
+	feeds.last_fetched_at AS last_fetched_at,
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS follower_count,
+	(SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS post_count,
+	(SELECT MAX(posts.published_at) FROM posts WHERE posts.feed_id = feeds.id) AS last_post_at,
+	EXISTS (
+		SELECT 1 FROM feed_follows
+		WHERE feed_follows.feed_id = feeds.id
+		AND feed_follows.user_id = sqlc.arg('user_id')
+	) AS followed
+FROM feeds
+LEFT JOIN users
+ON feeds.user_id = users.id
+ORDER BY feeds.name;
+
 -- name: GetFeedIDByUrl :one
 SELECT id
 FROM feeds
@@ -30,3 +51,50 @@ WHERE id = $1;
 SELECT id, url FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1;
+
+-- name: GetFeed :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: GetAllFeeds :many
+SELECT * FROM feeds
+ORDER BY name;
+
+-- name: GetFeedByUrl :one
+SELECT * FROM feeds
+WHERE url = $1;
+
+-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateFeedUrl :one
+UPDATE feeds
+SET url = $2, updated_at = NOW(), last_fetched_at = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: GetFeedDeleteImpact :one
+SELECT
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS follower_count,
+	(SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS post_count;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = sqlc.arg('new_user_id'), updated_at = NOW()
+WHERE user_id = sqlc.arg('old_user_id');
+
+-- name: DeleteAllFeeds :exec
+DELETE FROM feeds;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET keep_days = $2, keep_posts = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
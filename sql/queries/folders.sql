@@ -0,0 +1,32 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, name, user_id)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5
+	)
+	RETURNING *;
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1
+AND name = $2;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = sqlc.arg('new_name'), updated_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+AND name = sqlc.arg('old_name')
+RETURNING *;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE user_id = $1
+AND name = $2;
@@ -1,4 +1,6 @@
 -- name: CreatePost :one
+-- A post whose url is already stored, or was pruned, is skipped, and
+-- returns no row.
 INSERT INTO posts (
 	id,
 	created_at,
@@ -9,17 +11,73 @@ INSERT INTO posts (
 	published_at,
 	feed_id
 )
-VALUES (
-	$1,
+SELECT
+	$1::uuid,
 	NOW(),
 	NOW(),
-	$2,
-	$3,
-	$4,
-	$5,
-	$6
-	)
-	RETURNING *;
+	$2::text,
+	$3::text,
+	$4::text,
+	$5::timestamp,
+	$6::uuid
+WHERE NOT EXISTS (
+	SELECT 1 FROM pruned_posts
+	WHERE pruned_posts.url = $3
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING *;
+
+-- name: CreatePosts :many
+-- Stores a feed's posts in one statement.  Posts whose url is already
+-- stored, or was pruned, are skipped, so only the new posts are returned.
+INSERT INTO posts (
+	id,
+	created_at,
+	updated_at,
+	title,
+	url,
+	description,
+	published_at,
+	feed_id
+)
+SELECT
+	new_posts.id,
+	NOW(),
+	NOW(),
+	new_posts.title,
+	new_posts.url,
+	new_posts.description,
+	new_posts.published_at,
+	sqlc.arg('feed_id')
+FROM unnest(
+	sqlc.arg('ids')::uuid[],
+	sqlc.arg('titles')::text[],
+	sqlc.arg('urls')::text[],
+	sqlc.arg('descriptions')::text[],
+	sqlc.arg('published_ats')::timestamp[]
+) AS new_posts(id, title, url, description, published_at)
+WHERE NOT EXISTS (
+	SELECT 1 FROM pruned_posts
+	WHERE pruned_posts.url = new_posts.url
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING *;
+
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = $1;
+
+-- name: GetPostByNum :one
+SELECT * FROM posts
+WHERE num = $1;
+
+-- name: GetPostForUser :one
+-- Returns a post only if it is in a feed the user follows.
+SELECT posts.* FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE posts.id = $1
+AND feed_follows.user_id = $2;
 
 -- name: GetPostsForUser :many
 SELECT
@@ -30,9 +88,100 @@ SELECT
 	posts.url AS url,
 	posts.description AS description,
 	posts.published_at AS published_at,
-	posts.feed_id AS feed_id
+	posts.feed_id AS feed_id,
+	posts.num AS num,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url,
+	folders.name AS folder_name,
+	post_reads.read_at AS read_at,
+	post_stars.starred_at AS starred_at
 FROM posts
 INNER JOIN feed_follows
 ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
 ORDER BY posts.published_at DESC;
+
+-- name: GetPostsForUserInFolder :many
+SELECT
+	posts.id AS id,
+	posts.created_at AS created_at,
+	posts.updated_at AS updated_at,
+	posts.title AS title,
+	posts.url AS url,
+	posts.description AS description,
+	posts.published_at AS published_at,
+	posts.feed_id AS feed_id,
+	posts.num AS num,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url,
+	folders.name AS folder_name,
+	post_reads.read_at AS read_at,
+	post_stars.starred_at AS starred_at
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND feed_follows.folder_id = $2
+ORDER BY posts.published_at DESC;
+
+-- name: PrunePosts :execrows
+-- Deletes a feed's posts that were published before published_before, or
+-- are not among its newest keep_posts, and remembers their urls in
+-- pruned_posts.  A NULL limit is no limit.  Starred posts are always kept.
+-- Both statements see the posts as they were before either ran, so they
+-- pick the same posts, and the rows affected are the DELETE's.
+WITH pruned AS (
+	INSERT INTO pruned_posts (url, feed_id, pruned_at)
+	SELECT posts.url, posts.feed_id, NOW()
+	FROM posts
+	WHERE posts.feed_id = sqlc.arg('feed_id')
+	AND NOT EXISTS (
+		SELECT 1 FROM post_stars
+		WHERE post_stars.post_id = posts.id
+	)
+	AND (
+		posts.published_at < sqlc.narg('published_before')
+		OR posts.id NOT IN (
+			SELECT newest.id FROM posts AS newest
+			WHERE newest.feed_id = sqlc.arg('feed_id')
+			ORDER BY newest.published_at DESC
+			LIMIT sqlc.narg('keep_posts')
+		)
+	)
+	ON CONFLICT (url) DO NOTHING
+)
+DELETE FROM posts
+WHERE posts.feed_id = sqlc.arg('feed_id')
+AND NOT EXISTS (
+	SELECT 1 FROM post_stars
+	WHERE post_stars.post_id = posts.id
+)
+AND (
+	posts.published_at < sqlc.narg('published_before')
+	OR posts.id NOT IN (
+		SELECT newest.id FROM posts AS newest
+		WHERE newest.feed_id = sqlc.arg('feed_id')
+		ORDER BY newest.published_at DESC
+		LIMIT sqlc.narg('keep_posts')
+	)
+);
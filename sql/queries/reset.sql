@@ -0,0 +1,50 @@
+-- name: GetResetImpact :one
+SELECT
+	(SELECT COUNT(*) FROM users) AS user_count,
+	(SELECT COUNT(*) FROM api_tokens) AS token_count,
+	(SELECT COUNT(*) FROM feeds) AS feed_count,
+	(SELECT COUNT(*) FROM feeds WHERE last_fetched_at IS NOT NULL) AS fetched_feed_count,
+	(SELECT COUNT(*) FROM feed_follows) AS follow_count,
+	(SELECT COUNT(*) FROM folders) AS folder_count,
+	(SELECT COUNT(*) FROM posts) AS post_count,
+	(SELECT COUNT(*) FROM post_reads) AS read_count,
+	(SELECT COUNT(*) FROM post_stars) AS star_count,
+	(SELECT COUNT(*) FROM pruned_posts) AS pruned_post_count;
+
+-- name: GetUserResetImpact :one
+-- @param user_id: uuid
+SELECT
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = sqlc.arg('user_id')) AS follow_count,
+	(SELECT COUNT(*) FROM folders WHERE folders.user_id = sqlc.arg('user_id')) AS folder_count,
+	(SELECT COUNT(*) FROM post_reads WHERE post_reads.user_id = sqlc.arg('user_id')) AS read_count,
+	(SELECT COUNT(*) FROM post_stars WHERE post_stars.user_id = sqlc.arg('user_id')) AS star_count;
+
+-- name: DeleteAllPosts :execrows
+-- Read and starred marks go with the posts.
+DELETE FROM posts;
+
+-- name: DeleteAllPrunedPosts :execrows
+DELETE FROM pruned_posts;
+
+-- name: ClearFeedFetchTimes :execrows
+-- Every feed is treated as never fetched, so agg fetches them all again
+-- before any other.
+UPDATE feeds
+SET last_fetched_at = NULL
+WHERE last_fetched_at IS NOT NULL;
+
+-- name: DeleteFeedFollowsForUser :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1;
+
+-- name: DeleteFoldersForUser :execrows
+DELETE FROM folders
+WHERE user_id = $1;
+
+-- name: DeletePostReadsForUser :execrows
+DELETE FROM post_reads
+WHERE user_id = $1;
+
+-- name: DeletePostStarsForUser :execrows
+DELETE FROM post_stars
+WHERE user_id = $1;
@@ -0,0 +1,35 @@
+-- name: GetPostCountsByDay :many
+-- Days are returned as text, so every backend groups and reports them the
+-- same way.
+SELECT
+	feeds.id AS feed_id,
+	feeds.name AS feed_name,
+	to_char(posts.published_at, 'YYYY-MM-DD')::text AS day,
+	COUNT(*) AS post_count
+FROM posts
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+WHERE posts.published_at >= sqlc.arg('since')
+GROUP BY feeds.id, feeds.name, day
+ORDER BY feeds.name, day;
+
+-- name: GetReadRatios :many
+-- Counts the posts in each user's followed feeds, and how many of them
+-- the user has read.
+SELECT
+	users.name AS user_name,
+	COUNT(posts.id) AS post_count,
+	COUNT(post_reads.post_id) AS read_count
+FROM users
+LEFT JOIN feed_follows
+ON feed_follows.user_id = users.id
+LEFT JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = users.id
+GROUP BY users.id, users.name
+ORDER BY users.name;
+
+-- name: GetDatabaseSize :one
+SELECT pg_database_size(current_database())::bigint AS size;
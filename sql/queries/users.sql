@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
 VALUES (
 	$1,
 	$2,
 	$3,
-	$4
+	$4,
+	$5,
+	$6
 	)
 	RETURNING *;
 
@@ -17,3 +19,65 @@ DELETE FROM users;
 
 -- name: GetUsers :many
 SELECT name FROM users;
+
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY name;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RenameUser :one
+UPDATE users
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: GetUserStats :one
+-- @param user_id: uuid
+SELECT
+	(SELECT COUNT(*) FROM feeds WHERE feeds.user_id = sqlc.arg('user_id')) AS feeds_added,
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = sqlc.arg('user_id')) AS follow_count,
+	(SELECT COUNT(*) FROM folders WHERE folders.user_id = sqlc.arg('user_id')) AS folder_count,
+	(
+		SELECT COUNT(*) FROM posts
+		INNER JOIN feed_follows
+		ON feed_follows.feed_id = posts.feed_id
+		WHERE feed_follows.user_id = sqlc.arg('user_id')
+	) AS post_count,
+	(
+		SELECT COUNT(*) FROM posts
+		INNER JOIN feed_follows
+		ON feed_follows.feed_id = posts.feed_id
+		LEFT JOIN post_reads
+		ON post_reads.post_id = posts.id
+		AND post_reads.user_id = feed_follows.user_id
+		WHERE feed_follows.user_id = sqlc.arg('user_id')
+		AND post_reads.post_id IS NULL
+	) AS unread_count,
+	(SELECT COUNT(*) FROM post_stars WHERE post_stars.user_id = sqlc.arg('user_id')) AS starred_count,
+	(SELECT COUNT(*) FROM api_tokens WHERE api_tokens.user_id = sqlc.arg('user_id')) AS token_count;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: LockUsers :exec
+-- Held until the end of the transaction, so two registrations cannot both
+-- count no users and both become the first admin.  Reads are not blocked.
+LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin';
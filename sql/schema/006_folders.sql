@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE folders(
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	name TEXT NOT NULL,
+	user_id UUID NOT NULL,
+	CONSTRAINT fk_user_id
+	FOREIGN KEY (user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT unique_user_folder
+	UNIQUE (user_id, name)
+);
+
+ALTER TABLE feed_follows
+ADD COLUMN folder_id UUID
+CONSTRAINT fk_folder_id
+REFERENCES folders(id)
+ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN folder_id;
+
+DROP TABLE folders;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Reader apps identify items by number rather than UUID.
+ALTER TABLE posts
+ADD COLUMN num BIGSERIAL UNIQUE;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN num;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- The Fever API identifies feeds and groups by number rather than UUID.
+ALTER TABLE feeds
+ADD COLUMN num BIGSERIAL UNIQUE;
+
+ALTER TABLE folders
+ADD COLUMN num BIGSERIAL UNIQUE;
+
+-- +goose Down
+ALTER TABLE folders
+DROP COLUMN num;
+
+ALTER TABLE feeds
+DROP COLUMN num;
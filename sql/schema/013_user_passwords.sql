@@ -0,0 +1,9 @@
+-- +goose Up
+-- Users created before passwords existed have none, and cannot log in
+-- until an admin gives them one with "gator setpassword".
+ALTER TABLE users
+ADD COLUMN password_hash TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN password_hash;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- Feeds outlive the user who added them.  Deleting that user leaves the
+-- feed without an owner instead of deleting it for every follower.
+ALTER TABLE feeds
+ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE feeds
+DROP CONSTRAINT fk_user_id;
+
+ALTER TABLE feeds
+ADD CONSTRAINT fk_user_id
+FOREIGN KEY (user_id)
+REFERENCES users(id)
+ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM feeds
+WHERE user_id IS NULL;
+
+ALTER TABLE feeds
+DROP CONSTRAINT fk_user_id;
+
+ALTER TABLE feeds
+ADD CONSTRAINT fk_user_id
+FOREIGN KEY (user_id)
+REFERENCES users(id)
+ON DELETE CASCADE;
+
+ALTER TABLE feeds
+ALTER COLUMN user_id SET NOT NULL;
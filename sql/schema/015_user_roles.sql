@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+CONSTRAINT valid_role
+CHECK (role IN ('admin', 'user'));
+
+-- Someone has to be able to run admin commands; the first user registered
+-- is the most likely owner of the deployment.
+UPDATE users
+SET role = 'admin'
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN role;
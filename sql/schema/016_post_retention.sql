@@ -0,0 +1,25 @@
+-- +goose Up
+-- How long a feed's posts are kept.  NULL means the keep_days and
+-- keep_posts defaults in the config file apply.
+ALTER TABLE feeds
+ADD COLUMN keep_days INTEGER,
+ADD COLUMN keep_posts INTEGER;
+
+-- Posts are told apart by url, so a pruned post's url is remembered here
+-- to stop the next fetch from storing it again.
+CREATE TABLE pruned_posts(
+	url TEXT PRIMARY KEY,
+	feed_id UUID NOT NULL,
+	pruned_at TIMESTAMP NOT NULL,
+	CONSTRAINT fk_feed_id
+	FOREIGN KEY (feed_id)
+	REFERENCES feeds(id)
+	ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+
+ALTER TABLE feeds
+DROP COLUMN keep_days,
+DROP COLUMN keep_posts;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Where a token is accepted.  api tokens work everywhere, and fever keys,
+-- which are derived from a password, only in the Fever API.
+ALTER TABLE api_tokens
+ADD COLUMN scope TEXT NOT NULL DEFAULT 'api';
+
+UPDATE api_tokens
+SET scope = 'fever'
+WHERE name = 'fever';
+
+-- +goose Down
+ALTER TABLE api_tokens
+DROP COLUMN scope;
@@ -0,0 +1,9 @@
+// Package schema holds gator's database migrations.  They are goose
+// migrations, embedded so that "gator migrate" can apply them without
+// goose being installed.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,26 @@
+// Package sqlite holds the SQLite versions of gator's migrations and
+// queries.  Each query has the same name, parameters and result columns
+// as its Postgres original in sql/queries, so the code sqlc generates
+// from those can run either.
+package sqlite
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed schema/*.sql queries/*.sql
+var files embed.FS
+
+var (
+	Schema  = sub("schema")
+	Queries = sub("queries")
+)
+
+func sub(dir string) fs.FS {
+	fsys, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err)
+	}
+	return fsys
+}
@@ -0,0 +1,104 @@
+-- name: GetAllAPITokens :many
+SELECT id, created_at, user_id, name, token_hash, last_used_at, scope FROM api_tokens
+ORDER BY created_at, id;
+
+-- name: GetAllFolders :many
+SELECT id, created_at, updated_at, name, user_id, num FROM folders
+ORDER BY num;
+
+-- name: GetAllFeedFollows :many
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, title FROM feed_follows
+ORDER BY created_at, id;
+
+-- name: GetAllPosts :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, num FROM posts
+ORDER BY num;
+
+-- name: GetAllPostReads :many
+SELECT user_id, post_id, read_at FROM post_reads
+ORDER BY read_at, user_id, post_id;
+
+-- name: GetAllPostStars :many
+SELECT user_id, post_id, starred_at FROM post_stars
+ORDER BY starred_at, user_id, post_id;
+
+-- name: GetAllPrunedPosts :many
+SELECT url, feed_id, pruned_at FROM pruned_posts
+ORDER BY pruned_at, url;
+
+-- name: RestoreAPIToken :exec
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, last_used_at, scope)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7
+	);
+
+-- name: RestoreFeed :exec
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9,
+	$10
+	);
+
+-- name: RestoreFolder :exec
+INSERT INTO folders (id, created_at, updated_at, name, user_id, num)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
+	);
+
+-- name: RestoreFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id, title)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7
+	);
+
+-- name: RestorePost :exec
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, num)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9
+	);
+
+-- name: RestorePrunedPost :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+VALUES (
+	$1,
+	$2,
+	$3
+	);
+
+-- name: ResetNumSequences :exec
+-- The num columns are MAX(num) + 1 on SQLite, so there are no sequences
+-- to move past the restored rows.
+SELECT 1;
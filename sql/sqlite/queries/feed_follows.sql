@@ -0,0 +1,63 @@
+-- name: CreateFeedFollow :one
+-- SQLite has no INSERT in WITH, so the names come from subqueries in
+-- RETURNING instead.
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5
+	)
+	RETURNING
+	id, created_at, updated_at, user_id, feed_id, folder_id, title,
+	(SELECT feeds.name FROM feeds WHERE feeds.id = feed_id) AS feed_name,
+	(SELECT users.name FROM users WHERE users.id = user_id) AS user_name;
+
+-- name: DeleteFeedFollow :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1
+AND feed_id = $2;
+
+-- name: GetFeedFollowsForUser :many
+SELECT
+	feeds.id AS feed_id,
+	feeds.url AS feed_url,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	users.name as user_name,
+	folders.name AS folder_name,
+	feeds.num AS feed_num,
+	folders.num AS folder_num,
+	feeds.last_fetched_at AS last_fetched_at
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+INNER JOIN users
+ON users.id = feed_follows.user_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+WHERE users.name = $1
+ORDER BY folders.name NULLS FIRST, COALESCE(feed_follows.title, feeds.name);
+
+-- name: GetFeedFollowsForUserInFolder :many
+SELECT
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND feed_follows.folder_id = $2
+ORDER BY COALESCE(feed_follows.title, feeds.name);
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE user_id = $1
+AND feed_id = $2;
+
+-- name: SetFeedFollowTitle :execrows
+UPDATE feed_follows
+SET title = $3, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE user_id = $1
+AND feed_id = $2;
@@ -0,0 +1,109 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, num)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	(SELECT COALESCE(MAX(num), 0) + 1 FROM feeds)
+	)
+	RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts;
+
+-- name: DeleteAllFeeds :exec
+DELETE FROM feeds;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts FROM feeds
+ORDER BY name;
+
+-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts FROM feeds
+WHERE id = $1;
+
+-- name: GetFeedByUrl :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts FROM feeds
+WHERE url = $1;
+
+-- name: GetFeedCatalog :many
+SELECT
+	feeds.id AS id,
+	feeds.name AS name,
+	feeds.url AS url,
+	users.name AS user_name,
+	feeds.last_fetched_at AS last_fetched_at,
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS follower_count,
+	(SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS post_count,
+	last_post.published_at AS last_post_at,
+	EXISTS (
+		SELECT 1 FROM feed_follows
+		WHERE feed_follows.feed_id = feeds.id
+		AND feed_follows.user_id = $1
+	) AS followed
+FROM feeds
+LEFT JOIN users
+ON feeds.user_id = users.id
+-- Joined rather than selected with MAX, so the driver still sees a
+-- timestamp column.
+LEFT JOIN posts AS last_post
+ON last_post.id = (
+	SELECT posts.id FROM posts
+	WHERE posts.feed_id = feeds.id
+	ORDER BY posts.published_at DESC
+	LIMIT 1
+)
+ORDER BY feeds.name;
+
+-- name: GetFeedDeleteImpact :one
+SELECT
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS follower_count,
+	(SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS post_count;
+
+-- name: GetFeedIDByUrl :one
+SELECT id
+FROM feeds
+WHERE feeds.url = $1;
+
+-- name: GetFeeds :many
+SELECT feeds.id AS id, feeds.name AS name, feeds.url AS url, users.name AS user_name
+FROM feeds 
+LEFT JOIN users
+ON feeds.user_id = users.id;
+
+-- name: GetNextFeedToFetch :one
+SELECT id, url FROM feeds
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), last_fetched_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = $1, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE user_id = $2;
+
+-- name: UpdateFeedUrl :one
+UPDATE feeds
+SET url = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), last_fetched_at = NULL
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET keep_days = $2, keep_posts = $3, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, num, keep_days, keep_posts;
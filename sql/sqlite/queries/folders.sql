@@ -0,0 +1,33 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, name, user_id, num)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	(SELECT COALESCE(MAX(num), 0) + 1 FROM folders)
+	)
+	RETURNING id, created_at, updated_at, name, user_id, num;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE user_id = $1
+AND name = $2;
+
+-- name: GetFolderByName :one
+SELECT id, created_at, updated_at, name, user_id, num FROM folders
+WHERE user_id = $1
+AND name = $2;
+
+-- name: GetFoldersForUser :many
+SELECT id, created_at, updated_at, name, user_id, num FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = $1, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE user_id = $2
+AND name = $3
+RETURNING id, created_at, updated_at, name, user_id, num;
@@ -0,0 +1,184 @@
+-- name: CreatePost :one
+-- A post whose url is already stored, or was pruned, is skipped, and
+-- returns no row.
+INSERT INTO posts (
+	id,
+	created_at,
+	updated_at,
+	title,
+	url,
+	description,
+	published_at,
+	feed_id,
+	num
+)
+SELECT
+	$1,
+	strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+	strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	(SELECT COALESCE(MAX(num), 0) + 1 FROM posts)
+WHERE NOT EXISTS (
+	SELECT 1 FROM pruned_posts
+	WHERE pruned_posts.url = $3
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, num;
+
+-- name: CreatePosts :many
+-- Stores a feed's posts in one statement.  Posts whose url is already
+-- stored, or was pruned, are skipped, so only the new posts are returned.
+-- The arrays arrive as JSON, and are read in step by index.
+INSERT INTO posts (
+	id,
+	created_at,
+	updated_at,
+	title,
+	url,
+	description,
+	published_at,
+	feed_id,
+	num
+)
+SELECT
+	new_posts.value,
+	strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+	strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+	json_extract($3, '$[' || new_posts.key || ']'),
+	json_extract($4, '$[' || new_posts.key || ']'),
+	json_extract($5, '$[' || new_posts.key || ']'),
+	json_extract($6, '$[' || new_posts.key || ']'),
+	$1,
+	(SELECT COALESCE(MAX(num), 0) FROM posts) + new_posts.key + 1
+FROM json_each($2) AS new_posts
+WHERE NOT EXISTS (
+	SELECT 1 FROM pruned_posts
+	WHERE pruned_posts.url = json_extract($4, '$[' || new_posts.key || ']')
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, num;
+
+-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, num FROM posts
+WHERE id = $1;
+
+-- name: GetPostByNum :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, num FROM posts
+WHERE num = $1;
+
+-- name: GetPostForUser :one
+-- Returns a post only if it is in a feed the user follows.
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.num FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE posts.id = $1
+AND feed_follows.user_id = $2;
+
+-- name: GetPostsForUser :many
+SELECT
+	posts.id AS id,
+	posts.created_at AS created_at,
+	posts.updated_at AS updated_at,
+	posts.title AS title,
+	posts.url AS url,
+	posts.description AS description,
+	posts.published_at AS published_at,
+	posts.feed_id AS feed_id,
+	posts.num AS num,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url,
+	folders.name AS folder_name,
+	post_reads.read_at AS read_at,
+	post_stars.starred_at AS starred_at
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC;
+
+-- name: GetPostsForUserInFolder :many
+SELECT
+	posts.id AS id,
+	posts.created_at AS created_at,
+	posts.updated_at AS updated_at,
+	posts.title AS title,
+	posts.url AS url,
+	posts.description AS description,
+	posts.published_at AS published_at,
+	posts.feed_id AS feed_id,
+	posts.num AS num,
+	COALESCE(feed_follows.title, feeds.name) AS feed_name,
+	feeds.url AS feed_url,
+	folders.name AS folder_name,
+	post_reads.read_at AS read_at,
+	post_stars.starred_at AS starred_at
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON feeds.id = posts.feed_id
+LEFT JOIN folders
+ON folders.id = feed_follows.folder_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND feed_follows.folder_id = $2
+ORDER BY posts.published_at DESC;
+
+-- name: PrunePosts :execrows
+-- Deletes a feed's posts that were published before published_before, or
+-- are not among its newest keep_posts, and remembers their urls in
+-- pruned_posts.  A NULL limit is no limit.  Starred posts are always kept.
+-- SQLite has no DELETE in a WITH, so the urls are stored first, and the
+-- same posts deleted after; the rows affected are the DELETE's.
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT posts.url, posts.feed_id, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+FROM posts
+WHERE posts.feed_id = $1
+AND NOT EXISTS (
+	SELECT 1 FROM post_stars
+	WHERE post_stars.post_id = posts.id
+)
+AND (
+	posts.published_at < $2
+	OR posts.id NOT IN (
+		SELECT newest.id FROM posts AS newest
+		WHERE newest.feed_id = $1
+		ORDER BY newest.published_at DESC
+		LIMIT COALESCE($3, -1)
+	)
+)
+ON CONFLICT (url) DO NOTHING;
+DELETE FROM posts
+WHERE posts.feed_id = $1
+AND NOT EXISTS (
+	SELECT 1 FROM post_stars
+	WHERE post_stars.post_id = posts.id
+)
+AND (
+	posts.published_at < $2
+	OR posts.id NOT IN (
+		SELECT newest.id FROM posts AS newest
+		WHERE newest.feed_id = $1
+		ORDER BY newest.published_at DESC
+		LIMIT COALESCE($3, -1)
+	)
+);
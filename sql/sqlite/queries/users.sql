@@ -0,0 +1,83 @@
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin';
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
+	)
+	RETURNING id, created_at, updated_at, name, password_hash, role;
+
+-- name: DeleteAllUsers :exec
+DELETE FROM users;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: GetUser :one
+SELECT id, created_at, updated_at, name, password_hash, role FROM users
+WHERE name = $1;
+
+-- name: GetUserStats :one
+SELECT
+	(SELECT COUNT(*) FROM feeds WHERE feeds.user_id = $1) AS feeds_added,
+	(SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follow_count,
+	(SELECT COUNT(*) FROM folders WHERE folders.user_id = $1) AS folder_count,
+	(
+		SELECT COUNT(*) FROM posts
+		INNER JOIN feed_follows
+		ON feed_follows.feed_id = posts.feed_id
+		WHERE feed_follows.user_id = $1
+	) AS post_count,
+	(
+		SELECT COUNT(*) FROM posts
+		INNER JOIN feed_follows
+		ON feed_follows.feed_id = posts.feed_id
+		LEFT JOIN post_reads
+		ON post_reads.post_id = posts.id
+		AND post_reads.user_id = feed_follows.user_id
+		WHERE feed_follows.user_id = $1
+		AND post_reads.post_id IS NULL
+	) AS unread_count,
+	(SELECT COUNT(*) FROM post_stars WHERE post_stars.user_id = $1) AS starred_count,
+	(SELECT COUNT(*) FROM api_tokens WHERE api_tokens.user_id = $1) AS token_count;
+
+-- name: GetUsers :many
+SELECT name FROM users;
+
+-- name: ListUsers :many
+SELECT id, created_at, updated_at, name, password_hash, role FROM users
+ORDER BY name;
+
+-- name: LockUsers :exec
+-- A SQLite transaction that read before another one wrote cannot write
+-- itself, so two registrations cannot both become the first admin without
+-- a lock.
+SELECT 1;
+
+-- name: RenameUser :one
+UPDATE users
+SET name = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, password_hash, role;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, password_hash, role;
@@ -0,0 +1,140 @@
+-- +goose Up
+-- The SQLite schema starts where the Postgres migrations in sql/schema had
+-- got to by 015_user_roles.sql.  UUIDs are stored as text, and the num
+-- columns are filled in by the insert queries, since SQLite only
+-- autoincrements the primary key.
+CREATE TABLE users(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	name TEXT UNIQUE NOT NULL,
+	password_hash TEXT,
+	role TEXT NOT NULL DEFAULT 'user'
+	CONSTRAINT valid_role
+	CHECK (role IN ('admin', 'user'))
+);
+
+CREATE TABLE feeds(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	name TEXT UNIQUE NOT NULL,
+	url TEXT UNIQUE NOT NULL,
+	user_id TEXT,
+	last_fetched_at TIMESTAMP,
+	num INTEGER UNIQUE NOT NULL,
+	CONSTRAINT fk_user_id
+	FOREIGN KEY (user_id)
+	REFERENCES users(id)
+	ON DELETE SET NULL
+);
+
+CREATE TABLE folders(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	name TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	num INTEGER UNIQUE NOT NULL,
+	CONSTRAINT fk_user_id
+	FOREIGN KEY (user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT unique_user_folder
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follows(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	user_id TEXT NOT NULL,
+	feed_id TEXT NOT NULL,
+	folder_id TEXT,
+	title TEXT,
+	CONSTRAINT fk_user_id
+	FOREIGN KEY (user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT fk_feed_id
+	FOREIGN KEY (feed_id)
+	REFERENCES feeds(id)
+	ON DELETE CASCADE,
+	CONSTRAINT fk_folder_id
+	FOREIGN KEY (folder_id)
+	REFERENCES folders(id)
+	ON DELETE SET NULL,
+	CONSTRAINT unique_user_feed
+	UNIQUE (user_id, feed_id)
+);
+
+CREATE TABLE posts(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	title TEXT NOT NULL,
+	url TEXT UNIQUE NOT NULL,
+	description TEXT NOT NULL,
+	published_at TIMESTAMP NOT NULL,
+	feed_id TEXT NOT NULL,
+	num INTEGER UNIQUE NOT NULL,
+	CONSTRAINT fk_feed_id
+	FOREIGN KEY (feed_id)
+	REFERENCES feeds(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX posts_feed_id ON posts(feed_id);
+
+CREATE TABLE post_reads(
+	user_id TEXT NOT NULL,
+	post_id TEXT NOT NULL,
+	read_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, post_id),
+	CONSTRAINT fk_user_id
+	FOREIGN KEY (user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT fk_post_id
+	FOREIGN KEY (post_id)
+	REFERENCES posts(id)
+	ON DELETE CASCADE
+);
+
+CREATE TABLE post_stars(
+	user_id TEXT NOT NULL,
+	post_id TEXT NOT NULL,
+	starred_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, post_id),
+	CONSTRAINT fk_user_id
+	FOREIGN KEY (user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT fk_post_id
+	FOREIGN KEY (post_id)
+	REFERENCES posts(id)
+	ON DELETE CASCADE
+);
+
+CREATE TABLE api_tokens(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT UNIQUE NOT NULL,
+	last_used_at TIMESTAMP,
+	CONSTRAINT fk_user_id
+	FOREIGN KEY (user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
+DROP TABLE post_stars;
+DROP TABLE post_reads;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE folders;
+DROP TABLE feeds;
+DROP TABLE users;
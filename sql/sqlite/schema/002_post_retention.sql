@@ -0,0 +1,20 @@
+-- +goose Up
+-- Mirrors 016_post_retention.sql.
+ALTER TABLE feeds ADD COLUMN keep_days INTEGER;
+ALTER TABLE feeds ADD COLUMN keep_posts INTEGER;
+
+CREATE TABLE pruned_posts(
+	url TEXT PRIMARY KEY,
+	feed_id TEXT NOT NULL,
+	pruned_at TIMESTAMP NOT NULL,
+	CONSTRAINT fk_feed_id
+	FOREIGN KEY (feed_id)
+	REFERENCES feeds(id)
+	ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+
+ALTER TABLE feeds DROP COLUMN keep_posts;
+ALTER TABLE feeds DROP COLUMN keep_days;
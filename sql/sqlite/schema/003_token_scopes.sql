@@ -0,0 +1,10 @@
+-- +goose Up
+-- Mirrors 017_token_scopes.sql.
+ALTER TABLE api_tokens ADD COLUMN scope TEXT NOT NULL DEFAULT 'api';
+
+UPDATE api_tokens
+SET scope = 'fever'
+WHERE name = 'fever';
+
+-- +goose Down
+ALTER TABLE api_tokens DROP COLUMN scope;
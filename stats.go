@@ -0,0 +1,258 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+const statsUsage = "Usage: gator stats [--by=day|week] [--days=<n>] [--top=<n>] [--stale=<n>] [--json]"
+
+type statsReport struct {
+	Since      time.Time        `json:"since"`
+	By         string           `json:"by"`
+	Feeds      []statsFeed      `json:"feeds"`
+	MostActive []statsFeedTotal `json:"most_active"`
+	StaleDays  int              `json:"stale_days"`
+	StaleFeeds []statsStaleFeed `json:"stale_feeds"`
+	ReadRatios []statsReadRatio `json:"read_ratios"`
+	// DatabaseSize is in bytes, and null for the memory backend.
+	DatabaseSize *int64 `json:"database_size"`
+}
+
+// statsFeed is how many posts a feed published in each day or week
+// since the start of the report.  Periods without posts are left out.
+type statsFeed struct {
+	ID      uuid.UUID     `json:"id"`
+	Name    string        `json:"name"`
+	Total   int64         `json:"total"`
+	Periods []statsPeriod `json:"periods"`
+}
+
+// statsPeriod is a day, or a week starting on Monday, as YYYY-MM-DD.
+type statsPeriod struct {
+	Start string `json:"start"`
+	Posts int64  `json:"posts"`
+}
+
+type statsFeedTotal struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Posts int64     `json:"posts"`
+}
+
+type statsStaleFeed struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	LastPostAt *time.Time `json:"last_post_at"`
+}
+
+type statsReadRatio struct {
+	User  string  `json:"user"`
+	Posts int64   `json:"posts"`
+	Read  int64   `json:"read"`
+	Ratio float64 `json:"ratio"`
+}
+
+// statsFlag reads a whole number flag of at least 1, or returns def if it
+// is not given.
+func statsFlag(flags map[string]string, name string, def int) (int, error) {
+	value, ok := flags[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("ERROR: --%v must be a number of at least 1.\n%v", name, statsUsage)
+	}
+	return n, nil
+}
+
+// weekStart returns the Monday of the week day is in.  Both are
+// YYYY-MM-DD.
+func weekStart(day string) string {
+	t, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return day
+	}
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset).Format(time.DateOnly)
+}
+
+func handleStats(s *state, cmd command, user database.User) error {
+	args, flags := parseFlags(cmd.args)
+	if len(args) > 0 {
+		return fmt.Errorf("ERROR: stats takes no arguments.\n%v", statsUsage)
+	}
+	by := cmp.Or(flags["by"], "day")
+	if by != "day" && by != "week" {
+		return fmt.Errorf("ERROR: --by must be day or week.\n%v", statsUsage)
+	}
+	days, err := statsFlag(flags, "days", 30)
+	if err != nil {
+		return err
+	}
+	top, err := statsFlag(flags, "top", 10)
+	if err != nil {
+		return err
+	}
+	staleDays, err := statsFlag(flags, "stale", 30)
+	if err != nil {
+		return err
+	}
+
+	ctx := s.ctx
+	// Empty lists are written as [] rather than null in the JSON.
+	report := statsReport{
+		Feeds:      []statsFeed{},
+		MostActive: []statsFeedTotal{},
+		StaleFeeds: []statsStaleFeed{},
+		ReadRatios: []statsReadRatio{},
+	}
+	report.By = by
+	report.StaleDays = staleDays
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	report.Since = today.AddDate(0, 0, 1-days)
+
+	counts, err := s.db.GetPostCountsByDay(ctx, report.Since)
+	if err != nil {
+		fmt.Println("ERROR: Could not count posts per day.")
+		return err
+	}
+	for _, row := range counts {
+		if len(report.Feeds) == 0 || report.Feeds[len(report.Feeds)-1].ID != row.FeedID {
+			report.Feeds = append(report.Feeds, statsFeed{ID: row.FeedID, Name: row.FeedName})
+		}
+		feed := &report.Feeds[len(report.Feeds)-1]
+		feed.Total += row.PostCount
+		start := row.Day
+		if by == "week" {
+			start = weekStart(row.Day)
+		}
+		if n := len(feed.Periods); n > 0 && feed.Periods[n-1].Start == start {
+			feed.Periods[n-1].Posts += row.PostCount
+		} else {
+			feed.Periods = append(feed.Periods, statsPeriod{Start: start, Posts: row.PostCount})
+		}
+	}
+	for _, feed := range report.Feeds {
+		report.MostActive = append(report.MostActive, statsFeedTotal{ID: feed.ID, Name: feed.Name, Posts: feed.Total})
+	}
+	slices.SortStableFunc(report.MostActive, func(a, b statsFeedTotal) int {
+		return cmp.Compare(b.Posts, a.Posts)
+	})
+	report.MostActive = report.MostActive[:min(top, len(report.MostActive))]
+
+	catalog, err := s.db.GetFeedCatalog(ctx, uuid.Nil)
+	if err != nil {
+		fmt.Println("ERROR: Could not retrieve feeds from database.")
+		return err
+	}
+	staleSince := time.Now().AddDate(0, 0, -staleDays)
+	for _, feed := range catalog {
+		if feed.LastPostAt.Valid && feed.LastPostAt.Time.After(staleSince) {
+			continue
+		}
+		report.StaleFeeds = append(report.StaleFeeds, statsStaleFeed{ID: feed.ID, Name: feed.Name, LastPostAt: nullTimePtr(feed.LastPostAt)})
+	}
+
+	ratios, err := s.db.GetReadRatios(ctx)
+	if err != nil {
+		fmt.Println("ERROR: Could not count read posts.")
+		return err
+	}
+	for _, row := range ratios {
+		// How much other users read is only shown to admins.
+		if user.Role != roleAdmin && row.UserName != user.Name {
+			continue
+		}
+		ratio := statsReadRatio{User: row.UserName, Posts: row.PostCount, Read: row.ReadCount}
+		if row.PostCount > 0 {
+			ratio.Ratio = float64(row.ReadCount) / float64(row.PostCount)
+		}
+		report.ReadRatios = append(report.ReadRatios, ratio)
+	}
+
+	if s.backend.db != nil {
+		size, err := s.db.GetDatabaseSize(ctx)
+		if err != nil {
+			fmt.Println("ERROR: Could not get database size.")
+			return err
+		}
+		report.DatabaseSize = &size
+	}
+
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(report)
+	}
+	printStats(report)
+	return nil
+}
+
+func printStats(report statsReport) {
+	since := report.Since.Format(time.DateOnly)
+	fmt.Printf("Posts per %v since %v:\n", report.By, since)
+	if len(report.Feeds) == 0 {
+		fmt.Println("  No posts.")
+	}
+	for _, feed := range report.Feeds {
+		fmt.Printf("* %v: %v posts\n", feed.Name, feed.Total)
+		for _, period := range feed.Periods {
+			fmt.Printf("    %v  %v\n", period.Start, period.Posts)
+		}
+	}
+	fmt.Printf("\nMost active feeds since %v:\n", since)
+	if len(report.MostActive) == 0 {
+		fmt.Println("  No posts.")
+	}
+	for i, feed := range report.MostActive {
+		fmt.Printf("  %v. %v: %v posts\n", i+1, feed.Name, feed.Posts)
+	}
+	fmt.Printf("\nFeeds with no posts in %v days:\n", report.StaleDays)
+	if len(report.StaleFeeds) == 0 {
+		fmt.Println("  None.")
+	}
+	for _, feed := range report.StaleFeeds {
+		if feed.LastPostAt != nil {
+			fmt.Printf("* %v (last post %v)\n", feed.Name, feed.LastPostAt.Format(time.DateOnly))
+		} else {
+			fmt.Printf("* %v (no posts)\n", feed.Name)
+		}
+	}
+	fmt.Println("\nRead ratio:")
+	for _, ratio := range report.ReadRatios {
+		fmt.Printf("* %v: %v of %v posts read (%.0f%%)\n", ratio.User, ratio.Read, ratio.Posts, ratio.Ratio*100)
+	}
+	fmt.Println()
+	if report.DatabaseSize != nil {
+		fmt.Println("Database size:", formatBytes(*report.DatabaseSize))
+	} else {
+		fmt.Println("Database size: n/a, nothing is stored on disk.")
+	}
+}
+
+// formatBytes gives a size in the largest unit it is at least one of,
+// like "12.3 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%v B", n)
+	}
+	size := float64(n) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if size < unit {
+			return fmt.Sprintf("%.1f %v", size, suffix)
+		}
+		size /= unit
+	}
+	return fmt.Sprintf("%.1f TiB", size)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lucoand/gator/internal/database"
+)
+
+// maxOutputEntries caps how many posts go into a generated Atom or RSS
+// feed.  Readers only need the recent ones.
+const maxOutputEntries = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Link      atomLink     `xml:"link"`
+	Category  atomCategory `xml:"category"`
+	Summary   atomText     `xml:"summary"`
+}
+
+type rssOutput struct {
+	XMLName xml.Name         `xml:"rss"`
+	Version string           `xml:"version,attr"`
+	Channel rssOutputChannel `xml:"channel"`
+}
+
+type rssOutputChannel struct {
+	Title         string          `xml:"title"`
+	Link          string          `xml:"link"`
+	Description   string          `xml:"description"`
+	LastBuildDate string          `xml:"lastBuildDate,omitempty"`
+	Items         []rssOutputItem `xml:"item"`
+}
+
+type rssOutputItem struct {
+	Title       string      `xml:"title"`
+	Link        string      `xml:"link"`
+	GUID        rssOutputID `xml:"guid"`
+	Description string      `xml:"description"`
+	PubDate     string      `xml:"pubDate"`
+	Category    string      `xml:"category"`
+}
+
+type rssOutputID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (api *apiServer) handleUserAtom(w http.ResponseWriter, r *http.Request) {
+	api.serveOutputFeed(w, r, "atom")
+}
+
+func (api *apiServer) handleUserRSS(w http.ResponseWriter, r *http.Request) {
+	api.serveOutputFeed(w, r, "rss")
+}
+
+// feedUser is pathUser for the Atom and RSS feeds.  Besides a Bearer
+// token or a web session, they take HTTP Basic auth with the user name and
+// a token from "gator newtoken --feed", which ordinary feed readers can
+// send.  Without credentials, the 401 asks for Basic auth so readers
+// prompt for them.
+func (api *apiServer) feedUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	name, token, found := r.BasicAuth()
+	if !found {
+		if requestToken(r) == "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gator", charset="UTF-8"`)
+		}
+		return api.pathUser(w, r)
+	}
+	user, err := userForScopedToken(r.Context(), api.s, tokenScopeFeed, token)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && user.Name != name {
+		w.Header().Set("WWW-Authenticate", `Basic realm="gator", charset="UTF-8"`)
+		respondWithError(w, http.StatusUnauthorized, "wrong user name or feed token")
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithDBError(w, err)
+		return database.User{}, false
+	}
+	if user.Name != r.PathValue("name") {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("token does not belong to user %v", r.PathValue("name")))
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// serveOutputFeed writes the user's timeline, or one folder of it, as an
+// Atom or RSS feed.  Conditional GETs are answered with 304 Not Modified
+// using both the ETag and the Last-Modified time.
+func (api *apiServer) serveOutputFeed(w http.ResponseWriter, r *http.Request, format string) {
+	user, ok := api.feedUser(w, r)
+	if !ok {
+		return
+	}
+	folder := r.PathValue("folder")
+	posts, err := postsForUser(r.Context(), api.s, user, postFilter{Folder: folder})
+	if err != nil {
+		respondWithDBError(w, err)
+		return
+	}
+	if len(posts) > maxOutputEntries {
+		posts = posts[:maxOutputEntries]
+	}
+
+	title := "gator: " + user.Name
+	feedID := "urn:uuid:" + user.ID.String()
+	if folder != "" {
+		// A folder's feed is identified by the folder, so it keeps its
+		// ID when the folder is renamed.
+		var arg database.GetFolderByNameParams
+		arg.UserID = user.ID
+		arg.Name = folder
+		f, err := api.s.db.GetFolderByName(r.Context(), arg)
+		if err != nil {
+			respondWithDBError(w, err)
+			return
+		}
+		title += " / " + folder
+		feedID = "urn:uuid:" + f.ID.String()
+	}
+	selfURL := requestURL(r)
+
+	// Posts are never edited once stored, so the newest created_at is when
+	// this feed last changed.  Renames and custom titles still change the
+	// ETag, since it is computed from the body.
+	var lastModified time.Time
+	for _, post := range posts {
+		if post.CreatedAt.After(lastModified) {
+			lastModified = post.CreatedAt
+		}
+	}
+	lastModified = lastModified.UTC().Truncate(time.Second)
+
+	var body []byte
+	var contentType string
+	if format == "atom" {
+		body, err = xml.MarshalIndent(buildAtomFeed(feedID, title, selfURL, user.Name, lastModified, posts), "", "  ")
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		body, err = xml.MarshalIndent(buildRSSFeed(title, selfURL, lastModified, posts), "", "  ")
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "could not generate feed")
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	// ServeContent handles If-None-Match and If-Modified-Since for us.
+	http.ServeContent(w, r, "", lastModified, bytes.NewReader(body))
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%v://%v%v", scheme, r.Host, r.URL.Path)
+}
+
+func buildAtomFeed(id, title, selfURL, author string, updated time.Time, posts []database.GetPostsForUserRow) atomFeed {
+	if updated.IsZero() {
+		updated = time.Unix(0, 0).UTC()
+	}
+	feed := atomFeed{
+		ID:      id,
+		Title:   title,
+		Updated: updated.Format(time.RFC3339),
+		Links:   []atomLink{{Href: selfURL, Rel: "self", Type: "application/atom+xml"}},
+		Author:  atomAuthor{Name: author},
+	}
+	for _, post := range posts {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        "urn:uuid:" + post.ID.String(),
+			Title:     post.Title,
+			Updated:   post.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: post.PublishedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: post.Url, Rel: "alternate"},
+			Category:  atomCategory{Term: post.FeedName},
+			Summary:   atomText{Type: "html", Body: post.Description},
+		})
+	}
+	return feed
+}
+
+func buildRSSFeed(title, selfURL string, updated time.Time, posts []database.GetPostsForUserRow) rssOutput {
+	feed := rssOutput{
+		Version: "2.0",
+		Channel: rssOutputChannel{
+			Title:       title,
+			Link:        selfURL,
+			Description: "Posts collected by gator",
+		},
+	}
+	if !updated.IsZero() {
+		feed.Channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+	for _, post := range posts {
+		feed.Channel.Items = append(feed.Channel.Items, rssOutputItem{
+			Title:       post.Title,
+			Link:        post.Url,
+			GUID:        rssOutputID{IsPermaLink: "false", Value: "urn:uuid:" + post.ID.String()},
+			Description: post.Description,
+			PubDate:     post.PublishedAt.UTC().Format(time.RFC1123Z),
+			Category:    post.FeedName,
+		})
+	}
+	return feed
+}
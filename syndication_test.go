@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func TestOutputFeedAuth(t *testing.T) {
+	basic := func(name, token string) http.Header {
+		credentials := base64.StdEncoding.EncodeToString([]byte(name + ":" + token))
+		return http.Header{"Authorization": {"Basic " + credentials}}
+	}
+	forEachBackend(t, func(t *testing.T, s *state) {
+		alice, _ := addTestBlog(t, s)
+		bob := addTestUser(t, s, "bob")
+		if err := storeToken(s.ctx, s, alice, "reader", tokenScopeFeed, "alice-feed-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		if err := storeToken(s.ctx, s, alice, "laptop", tokenScopeAPI, "alice-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		if err := storeToken(s.ctx, s, bob, "reader", tokenScopeFeed, "bob-feed-token"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		server := newTestServer(t, s)
+
+		tests := []struct {
+			name   string
+			path   string
+			header http.Header
+			want   int
+		}{
+			{name: "feed token", path: "/feeds/alice/atom.xml", header: basic("alice", "alice-feed-token"), want: http.StatusOK},
+			{name: "feed token rss", path: "/feeds/alice/rss.xml", header: basic("alice", "alice-feed-token"), want: http.StatusOK},
+			{name: "bearer api token", path: "/feeds/alice/atom.xml", header: http.Header{"Authorization": {"Bearer alice-token"}}, want: http.StatusOK},
+			{name: "no credentials", path: "/feeds/alice/atom.xml", want: http.StatusUnauthorized},
+			{name: "wrong token", path: "/feeds/alice/atom.xml", header: basic("alice", "guess"), want: http.StatusUnauthorized},
+			{name: "wrong user name", path: "/feeds/alice/atom.xml", header: basic("bob", "alice-feed-token"), want: http.StatusUnauthorized},
+			{name: "api token as password", path: "/feeds/alice/atom.xml", header: basic("alice", "alice-token"), want: http.StatusUnauthorized},
+			{name: "feed token in query", path: "/feeds/alice/atom.xml?token=alice-feed-token", want: http.StatusUnauthorized},
+			{name: "other user's feed", path: "/feeds/alice/atom.xml", header: basic("bob", "bob-feed-token"), want: http.StatusForbidden},
+			{name: "feed token in api", path: "/api/users/alice/posts", header: http.Header{"Authorization": {"Bearer alice-feed-token"}}, want: http.StatusUnauthorized},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := testRequest(t, server, "GET", tt.path, tt.header, nil)
+				if status != tt.want {
+					t.Errorf("status %v, want %v: %v", status, tt.want, body)
+				}
+			})
+		}
+	})
+}
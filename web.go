@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucoand/gator/internal/database"
+)
+
+// The web UI is plain server-rendered HTML.  Everything is a link or a
+// form post, so it works without any JavaScript.
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var webTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"plainText": plainText,
+}).ParseFS(templateFS, "templates/*.html"))
+
+const (
+	webSessionCookie = "gator_session"
+
+	// webMaxPosts caps the post list, like the limit argument of browse.
+	webMaxPosts = 200
+)
+
+// webFilter is what the reader page is currently showing.  It lives in
+// the query string so every view can be bookmarked.
+type webFilter struct {
+	Feed    string
+	Folder  string
+	Unread  bool
+	Starred bool
+	Post    string
+}
+
+type webFeed struct {
+	ID     uuid.UUID
+	Name   string
+	Unread int
+}
+
+// webFolder is one group in the sidebar.  Feeds that are in no folder are
+// grouped under an empty name.
+type webFolder struct {
+	Name   string
+	Unread int
+	Feeds  []webFeed
+}
+
+type webPage struct {
+	Title       string
+	Error       string
+	User        database.User
+	Folders     []webFolder
+	TotalUnread int
+	Posts       []database.GetPostsForUserRow
+	Post        *database.GetPostsForUserRow
+	Filter      webFilter
+	Next        string
+}
+
+func (api *apiServer) registerWebRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /{$}", api.handleWebReader)
+	mux.HandleFunc("GET /login", api.handleWebLoginForm)
+	mux.HandleFunc("POST /login", api.handleWebLogin)
+	mux.HandleFunc("POST /logout", api.handleWebLogout)
+	mux.HandleFunc("POST /posts/{postID}/read", api.handleWebMarkRead)
+	mux.HandleFunc("POST /posts/{postID}/star", api.handleWebMarkStarred)
+}
+
+func (f webFilter) query() url.Values {
+	query := url.Values{}
+	if f.Feed != "" {
+		query.Set("feed", f.Feed)
+	}
+	if f.Folder != "" {
+		query.Set("folder", f.Folder)
+	}
+	if f.Unread {
+		query.Set("unread", "true")
+	}
+	if f.Starred {
+		query.Set("starred", "true")
+	}
+	if f.Post != "" {
+		query.Set("post", f.Post)
+	}
+	return query
+}
+
+func (f webFilter) URL() string {
+	query := f.query().Encode()
+	if query == "" {
+		return "/"
+	}
+	return "/?" + query
+}
+
+// Link returns the page for one feed or folder, or for everything when
+// both are empty, keeping the unread and starred toggles.
+func (f webFilter) Link(feed, folder string) string {
+	f.Feed = feed
+	f.Folder = folder
+	f.Post = ""
+	return f.URL()
+}
+
+// Toggle flips the unread or starred toggle.
+func (f webFilter) Toggle(name string) string {
+	switch name {
+	case "unread":
+		f.Unread = !f.Unread
+	case "starred":
+		f.Starred = !f.Starred
+	}
+	return f.URL()
+}
+
+// Open returns the current page with a post in the reading pane.
+func (f webFilter) Open(post string) string {
+	f.Post = post
+	return f.URL()
+}
+
+func renderWebPage(w http.ResponseWriter, status int, name string, page webPage) {
+	if page.Title == "" {
+		page.Title = "gator"
+	}
+	var buf bytes.Buffer
+	err := webTemplates.ExecuteTemplate(&buf, name, page)
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// webUser returns the user logged in to the web UI, or redirects to the
+// login page.  The session cookie holds an API token made at login.
+func (api *apiServer) webUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	cookie, err := r.Cookie(webSessionCookie)
+	if err == nil {
+		user, err := userForToken(r.Context(), api.s, cookie.Value)
+		if err == nil {
+			return user, true
+		}
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	return database.User{}, false
+}
+
+func (api *apiServer) handleWebLoginForm(w http.ResponseWriter, r *http.Request) {
+	renderWebPage(w, http.StatusOK, "login", webPage{Title: "gator: log in"})
+}
+
+func (api *apiServer) handleWebLogin(w http.ResponseWriter, r *http.Request) {
+	user, err := authenticate(r.Context(), api.s, r.FormValue("name"), r.FormValue("password"))
+	if errors.Is(err, errWrongPassword) {
+		renderWebPage(w, http.StatusUnauthorized, "login", webPage{Title: "gator: log in", Error: "Wrong user name or password."})
+		return
+	}
+	var token string
+	if err == nil {
+		token, err = createToken(r.Context(), api.s, user, webTokenName)
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webSessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleWebLogout revokes the session's token as well as clearing the
+// cookie.
+func (api *apiServer) handleWebLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(webSessionCookie); err == nil {
+		err = api.s.db.DeleteAPITokenByHash(r.Context(), hashToken(cookie.Value))
+		if err != nil {
+			log.Printf("Database error: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: webSessionCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// handleWebReader renders the three pane reader: followed feeds with
+// unread counts, the posts matching the filter, and the open post.
+// Opening a post marks it read.
+func (api *apiServer) handleWebReader(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.webUser(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	page := webPage{User: user, Next: r.URL.RequestURI()}
+	page.Filter.Feed = query.Get("feed")
+	page.Filter.Folder = query.Get("folder")
+	page.Filter.Unread = query.Get("unread") == "true"
+	page.Filter.Starred = query.Get("starred") == "true"
+	page.Filter.Post = query.Get("post")
+
+	follows, err := api.s.db.GetFeedFollowsForUser(r.Context(), user.Name)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	unread, err := postsForUser(r.Context(), api.s, user, postFilter{UnreadOnly: true})
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	page.TotalUnread = len(unread)
+	page.Folders = webSidebar(follows, unread)
+
+	var filter postFilter
+	filter.Folder = page.Filter.Folder
+	filter.UnreadOnly = page.Filter.Unread
+	filter.StarredOnly = page.Filter.Starred
+	posts, err := postsForUser(r.Context(), api.s, user, filter)
+	if err != nil {
+		page.Error = err.Error()
+	}
+	for _, post := range posts {
+		if page.Filter.Feed != "" && post.FeedID.String() != page.Filter.Feed {
+			continue
+		}
+		if post.ID.String() == page.Filter.Post {
+			open := post
+			if !open.ReadAt.Valid {
+				err = setPostRead(r.Context(), api.s, user, open.ID, true)
+				if err != nil {
+					log.Printf("Database error: %v", err)
+				} else {
+					open.ReadAt = sql.NullTime{Time: time.Now(), Valid: true}
+				}
+			}
+			page.Post = &open
+		}
+		if len(page.Posts) < webMaxPosts {
+			page.Posts = append(page.Posts, post)
+		}
+	}
+	renderWebPage(w, http.StatusOK, "reader", page)
+}
+
+func webSidebar(follows []database.GetFeedFollowsForUserRow, unread []database.GetPostsForUserRow) []webFolder {
+	counts := map[uuid.UUID]int{}
+	for _, post := range unread {
+		counts[post.FeedID]++
+	}
+	var folders []webFolder
+	for _, follow := range follows {
+		// Follows come sorted by folder, with unfiled feeds first.
+		name := follow.FolderName.String
+		if len(folders) == 0 || folders[len(folders)-1].Name != name {
+			folders = append(folders, webFolder{Name: name})
+		}
+		folder := &folders[len(folders)-1]
+		folder.Feeds = append(folder.Feeds, webFeed{ID: follow.FeedID, Name: follow.FeedName, Unread: counts[follow.FeedID]})
+		folder.Unread += counts[follow.FeedID]
+	}
+	return folders
+}
+
+func (api *apiServer) handleWebMarkRead(w http.ResponseWriter, r *http.Request) {
+	api.webMarkPost(w, r, setPostRead)
+}
+
+func (api *apiServer) handleWebMarkStarred(w http.ResponseWriter, r *http.Request) {
+	api.webMarkPost(w, r, setPostStarred)
+}
+
+// webMarkPost applies a read or star form and sends the browser back to
+// the page it came from.
+func (api *apiServer) webMarkPost(w http.ResponseWriter, r *http.Request, mark func(context.Context, *state, database.User, uuid.UUID, bool) error) {
+	user, ok := api.webUser(w, r)
+	if !ok {
+		return
+	}
+	postID, err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		http.Error(w, "invalid post id", http.StatusBadRequest)
+		return
+	}
+	err = mark(r.Context(), api.s, user, postID, r.FormValue("set") == "true")
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "post not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, "could not update post", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, localRedirect(r.FormValue("next")), http.StatusSeeOther)
+}
+
+// localRedirect returns next if it is a path on this site, and "/"
+// otherwise, so a form cannot redirect off site.  Browsers read a
+// backslash as a slash, so "/\evil.com" would go to evil.com, and
+// backslashes are refused outright.
+func localRedirect(next string) string {
+	if strings.Contains(next, "\\") {
+		return "/"
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		return "/"
+	}
+	return next
+}
+
+// plainText strips the markup from a feed's description.  Descriptions
+// come from other sites, so they are shown as text rather than trusted
+// as HTML.
+func plainText(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+			b.WriteRune(' ')
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(html.UnescapeString(b.String()))
+}
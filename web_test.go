@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLocalRedirect(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{next: "/", want: "/"},
+		{next: "/?filter=unread&post=abc", want: "/?filter=unread&post=abc"},
+		{next: "", want: "/"},
+		{next: "relative", want: "/"},
+		{next: "//evil.com", want: "/"},
+		{next: "/\\evil.com", want: "/"},
+		{next: "\\\\evil.com", want: "/"},
+		{next: "/\t/evil.com", want: "/"},
+		{next: "https://evil.com/", want: "/"},
+		{next: "javascript:alert(1)", want: "/"},
+	}
+	for _, tt := range tests {
+		if got := localRedirect(tt.next); got != tt.want {
+			t.Errorf("localRedirect(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestWebMarkPostNeedsFollow(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *state) {
+		alice, feed := addTestBlog(t, s)
+		bob := addTestUser(t, s, "bob")
+		post := addTestPosts(t, s, feed, day, "https://example.com/a")[0]
+		if err := storeToken(s.ctx, s, alice, webTokenName, tokenScopeAPI, "alice-session"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		if err := storeToken(s.ctx, s, bob, webTokenName, tokenScopeAPI, "bob-session"); err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+		server := newTestServer(t, s)
+		mark := func(session, action string) int {
+			t.Helper()
+			header := http.Header{
+				"Cookie":       {webSessionCookie + "=" + session},
+				"Content-Type": {"application/x-www-form-urlencoded"},
+			}
+			form := url.Values{"set": {"true"}, "next": {"/"}}
+			status, _ := testRequest(t, server, "POST", "/posts/"+post.ID.String()+"/"+action, header, strings.NewReader(form.Encode()))
+			return status
+		}
+
+		for _, action := range []string{"read", "star"} {
+			if status := mark("bob-session", action); status != http.StatusNotFound {
+				t.Errorf("bob marking %v an unfollowed post: status %v, want 404", action, status)
+			}
+			// alice is sent back to the reader page.
+			if status := mark("alice-session", action); status != http.StatusOK {
+				t.Errorf("alice marking %v a followed post: status %v, want 200", action, status)
+			}
+		}
+		impact, err := s.db.GetUserResetImpact(s.ctx, bob.ID)
+		if err != nil {
+			t.Fatalf("count rows: %v", err)
+		}
+		if impact.ReadCount != 0 || impact.StarCount != 0 {
+			t.Errorf("bob has %v reads and %v stars, want none", impact.ReadCount, impact.StarCount)
+		}
+	})
+}